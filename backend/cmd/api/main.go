@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 	"time"
 
 	"web-service-gin/backend/internal/album"
+	"web-service-gin/backend/internal/apikey"
 	"web-service-gin/backend/internal/chat"
 	"web-service-gin/backend/internal/middleware"
 	"web-service-gin/backend/internal/platform/database"
@@ -36,31 +38,105 @@ func main() {
 	}
 	defer db.Close()
 
+	// "api migrate <command>" manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(ctx, db, os.Args[2:]); err != nil {
+			log.Fatal("Migration failed: ", err)
+		}
+		return
+	}
+
 	// Run migrations
 	if err := db.Migrate(ctx); err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
 	// Initialize album domain
+	albumConfig, err := album.LoadConfig()
+	if err != nil {
+		log.Fatal("Invalid album configuration:", err)
+	}
 	albumRepo := album.NewRepository(db.Pool)
-	albumHandler := album.NewHandler(albumRepo)
+	albumHandler := album.NewHandler(albumRepo, album.NewRateRepository(db.Pool), albumConfig)
+
+	// Purge albums that have been in the trash past the retention period
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go album.RunRetention(jobsCtx, albumRepo, albumConfig)
+
+	// Initialize chat domain (optional when no provider is configured)
+	chatConfig, err := chat.LoadConfig()
+	if err != nil {
+		log.Fatal("Invalid chat configuration:", err)
+	}
+	if len(chatConfig.ActionSecret) == 0 {
+		log.Println("Warning: CHAT_ACTION_SECRET not set, pending chat actions will not survive a restart")
+	}
+
+	var chatHandler *chat.Handler
+	provider, err := chat.NewProviderFromEnv()
+	switch {
+	case err == nil:
+		chatService := chat.NewService(provider, albumRepo, chat.NewConversationRepository(db.Pool), chatConfig)
+		chatHandler = chat.NewHandler(chatService)
+	case errors.Is(err, chat.ErrProviderNotConfigured):
+		log.Println("Warning: no chat provider configured, chat endpoints disabled")
+	default:
+		log.Fatal("Failed to initialize chat provider:", err)
+	}
+
+	// Initialize API keys for machine clients
+	apiKeyRepo := apikey.NewRepository(db.Pool)
+	apiKeyHandler := apikey.NewHandler(apiKeyRepo, albumConfig.AdminToken)
 
-	// Initialize chat domain
-	chatService := chat.NewService(albumRepo)
-	chatHandler := chat.NewHandler(chatService)
+	// Initialize authentication
+	authConfig, err := middleware.LoadAuthConfig()
+	if err != nil {
+		log.Fatal("Invalid auth configuration:", err)
+	}
+	authenticator, err := middleware.NewAuthenticator(authConfig)
+	if err != nil {
+		log.Fatal("Failed to initialize authentication:", err)
+	}
+	authenticator.UseAPIKeys(apikey.NewVerifier(apiKeyRepo))
+	if !authenticator.Required() {
+		log.Println("Warning: AUTH_JWT_SECRET, AUTH_JWKS_FILE and AUTH_REQUIRED not set, API is open to anonymous requests")
+	}
 
 	// Create Gin router
 	router := gin.Default()
 
 	// Add CORS middleware
-	router.Use(middleware.CORS())
+	corsConfig, err := middleware.LoadCORSConfig()
+	if err != nil {
+		log.Fatal("Invalid CORS configuration:", err)
+	}
+	cors, err := middleware.CORS(corsConfig)
+	if err != nil {
+		log.Fatal("Invalid CORS configuration:", err)
+	}
+	router.Use(cors)
+
+	// Setup routes. Scopes only limit API keys; token holders are limited
+	// by the role permissions each handler checks.
+	albumScopes := middleware.RequireScopeByMethod(middleware.ScopeAlbumsRead, middleware.ScopeAlbumsWrite)
 
-	// Setup routes
-	albumGroup := router.Group("/albums")
+	albumGroup := router.Group("/albums", authenticator.Authenticate(), middleware.ResolveTenant(), albumScopes)
 	albumHandler.RegisterRoutes(albumGroup)
 
-	chatGroup := router.Group("/chat")
-	chatHandler.RegisterRoutes(chatGroup)
+	// Exchange rates are deliberately global: every tenant converts with the
+	// same table, which has no tenant_id, so no tenant is resolved here.
+	rateGroup := router.Group("/exchange-rates", authenticator.Authenticate(), albumScopes)
+	albumHandler.RegisterRateRoutes(rateGroup)
+
+	apiKeyGroup := router.Group("/api-keys")
+	apiKeyHandler.RegisterRoutes(apiKeyGroup)
+
+	if chatHandler != nil {
+		chatGroup := router.Group("/chat", authenticator.Authenticate(), middleware.ResolveTenant(), middleware.RequireScope(middleware.ScopeChat))
+		chatGroup.Use(album.TrackActor(album.SourceChat))
+		chatHandler.RegisterRoutes(chatGroup)
+	}
 
 	// Get server port from environment variable, default to "8080"
 	port := os.Getenv("SERVER_PORT")
@@ -87,6 +163,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down server...")
+	stopJobs()
 
 	// Give outstanding requests a deadline for completion
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"web-service-gin/backend/internal/platform/database"
+)
+
+const migrateUsage = `usage: api migrate <command>
+
+commands:
+  up          apply all pending migrations
+  down [N]    roll back the last N migrations (default 1)
+  to VERSION  migrate up or down to VERSION (0 rolls back everything)
+  status      list migrations and report gaps, unknown versions or
+              applied migrations whose SQL has changed
+  redo        roll back and re-apply the latest migration
+  repair [VERSION...]
+              accept the current SQL of applied migrations after an
+              intentional edit (default: every drifted migration)`
+
+// runMigrate executes a migrate subcommand against the database
+func runMigrate(ctx context.Context, db *database.Database, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		return db.Migrate(ctx)
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			var err error
+			if n, err = strconv.Atoi(args[1]); err != nil {
+				return fmt.Errorf("invalid number of migrations %q", args[1])
+			}
+		}
+		return db.MigrateDown(ctx, n)
+	case "to":
+		if len(args) < 2 {
+			return errors.New("migrate to requires a VERSION")
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		return db.MigrateTo(ctx, version)
+	case "status":
+		return printMigrationStatus(ctx, db)
+	case "redo":
+		return db.Redo(ctx)
+	case "repair":
+		versions := make([]int, 0, len(args)-1)
+		for _, arg := range args[1:] {
+			version, err := strconv.Atoi(arg)
+			if err != nil {
+				return fmt.Errorf("invalid version %q", arg)
+			}
+			versions = append(versions, version)
+		}
+		return db.RepairChecksums(ctx, versions...)
+	default:
+		return fmt.Errorf("unknown migrate command %q\n\n%s", args[0], migrateUsage)
+	}
+}
+
+// printMigrationStatus writes a table of migrations and any problems to stdout
+func printMigrationStatus(ctx context.Context, db *database.Database) error {
+	report, err := db.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Current version: %d\n\n", report.CurrentVersion)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tSTATUS\tAPPLIED AT\tDESCRIPTION")
+	for _, m := range report.Migrations {
+		status, appliedAt := "pending", "-"
+		if m.Applied {
+			status = "applied"
+			appliedAt = m.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if m.Drifted {
+			status = "modified"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", m.Version, status, appliedAt, m.Description)
+	}
+	w.Flush()
+
+	if len(report.Problems) > 0 {
+		fmt.Println("\nProblems:")
+		for _, problem := range report.Problems {
+			fmt.Printf("  - %s\n", problem)
+		}
+		return database.ErrMigrationState
+	}
+
+	return nil
+}
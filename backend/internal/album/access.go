@@ -0,0 +1,60 @@
+package album
+
+import (
+	"context"
+	"slices"
+
+	"web-service-gin/backend/internal/middleware"
+)
+
+// ChangePermissions returns the permissions needed to turn before into
+// after: creating when before is nil, deleting when after is nil, and
+// otherwise updating the title or artist and repricing the price, currency
+// or price points. An update that changes nothing still needs the update
+// permission.
+func ChangePermissions(before, after *Album) []middleware.Permission {
+	switch {
+	case before == nil:
+		return []middleware.Permission{middleware.PermAlbumsCreate}
+	case after == nil:
+		return []middleware.Permission{middleware.PermAlbumsDelete}
+	}
+
+	var permissions []middleware.Permission
+	if before.Title != after.Title || before.Artist != after.Artist {
+		permissions = append(permissions, middleware.PermAlbumsUpdate)
+	}
+	if !samePricing(before, after) {
+		permissions = append(permissions, middleware.PermAlbumsReprice)
+	}
+	if len(permissions) == 0 {
+		permissions = append(permissions, middleware.PermAlbumsUpdate)
+	}
+	return permissions
+}
+
+// AuthorizeChange checks that the caller carried by ctx may turn before into
+// after. It returns an error matching middleware.ErrForbidden otherwise.
+func AuthorizeChange(ctx context.Context, before, after *Album) error {
+	return middleware.Authorize(ctx, ChangePermissions(before, after)...)
+}
+
+// samePricing reports whether two albums have the same price, currency and
+// price points. An empty currency is the default one, as NormalizePricing
+// would make it.
+func samePricing(a, b *Album) bool {
+	currency := func(a *Album) string {
+		if a.Currency == "" {
+			return DefaultCurrency
+		}
+		return a.Currency
+	}
+	return a.Price == b.Price && currency(a) == currency(b) && slices.Equal(a.Prices, b.Prices)
+}
+
+// clone copies an album so it can be compared after the original is changed
+func clone(a *Album) *Album {
+	copied := *a
+	copied.Prices = slices.Clone(a.Prices)
+	return &copied
+}
@@ -0,0 +1,120 @@
+package album
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"web-service-gin/backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangePermissions(t *testing.T) {
+	album := &Album{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 5699, Currency: "USD"}
+	with := func(change func(a *Album)) *Album {
+		changed := clone(album)
+		change(changed)
+		return changed
+	}
+
+	tests := []struct {
+		name   string
+		before *Album
+		after  *Album
+		want   []middleware.Permission
+	}{
+		{"create", nil, album, []middleware.Permission{middleware.PermAlbumsCreate}},
+		{"delete", album, nil, []middleware.Permission{middleware.PermAlbumsDelete}},
+		{"title", album, with(func(a *Album) { a.Title = "Giant Steps" }), []middleware.Permission{middleware.PermAlbumsUpdate}},
+		{"price", album, with(func(a *Album) { a.Price = 999 }), []middleware.Permission{middleware.PermAlbumsReprice}},
+		{"price points", album, with(func(a *Album) { a.Prices = []PricePoint{{Currency: "EUR", Amount: 4999}} }), []middleware.Permission{middleware.PermAlbumsReprice}},
+		{"both", album, with(func(a *Album) { a.Artist = "Coltrane"; a.Currency = "EUR" }), []middleware.Permission{middleware.PermAlbumsUpdate, middleware.PermAlbumsReprice}},
+		{"no change", album, with(func(a *Album) {}), []middleware.Permission{middleware.PermAlbumsUpdate}},
+		{"default currency", album, with(func(a *Album) { a.Currency = "" }), []middleware.Permission{middleware.PermAlbumsUpdate}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ChangePermissions(tt.before, tt.after))
+		})
+	}
+}
+
+// newRoleRouter serves album routes to a caller holding the given role
+func newRoleRouter(repo Repository, role string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		middleware.SetPrincipal(c, &middleware.Principal{Subject: "sam", Roles: []string{role}})
+	})
+	NewHandler(repo, nil, Config{AdminToken: "secret"}).RegisterRoutes(router.Group("/albums"))
+	return router
+}
+
+func TestHandler_InternPermissions(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"browse", http.MethodGet, "/albums/1/history", "", http.StatusOK},
+		{"rename", http.MethodPatch, "/albums/1", `{"title": "Blue Train (Mono)"}`, http.StatusOK},
+		{"reprice", http.MethodPatch, "/albums/1", `{"price": 9.99}`, http.StatusForbidden},
+		{"reprice with PUT", http.MethodPut, "/albums/1", `{"title": "Blue Train", "artist": "John Coltrane", "price": 9.99}`, http.StatusForbidden},
+		{"create", http.MethodPost, "/albums", `{"title": "Giant Steps", "artist": "John Coltrane", "price": 17.99}`, http.StatusForbidden},
+		{"delete", http.MethodDelete, "/albums/1", "", http.StatusForbidden},
+		{"purge", http.MethodDelete, "/albums/1?hard=true", "", http.StatusForbidden},
+		{"revert", http.MethodPost, "/albums/1/revert", `{"revision_id": 1}`, http.StatusForbidden},
+		{"batch delete", http.MethodPost, "/albums/batch", `{"operations": [{"op": "update", "id": 1, "album": {"title": "Blue Train (Mono)"}}, {"op": "delete", "id": 1}]}`, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &historyTxRepo{txRepo: newTxRepo(Album{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 5699, Currency: "USD", Version: 1})}
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("If-Match", `"1"`)
+			w := httptest.NewRecorder()
+			newRoleRouter(repo, middleware.RoleIntern).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want, w.Code, w.Body.String())
+			if tt.want == http.StatusForbidden {
+				assert.Contains(t, w.Body.String(), "permission")
+				assert.Equal(t, Album{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 5699, Currency: "USD", Version: 1}, repo.albums[1])
+			}
+		})
+	}
+}
+
+func TestHandler_EditorPermissions(t *testing.T) {
+	repo := newTxRepo(Album{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 5699, Currency: "USD", Version: 1})
+	router := newRoleRouter(repo, middleware.RoleEditor)
+
+	req := httptest.NewRequest(http.MethodPatch, "/albums/1", strings.NewReader(`{"price": 9.99}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"1"`)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, Money(999), repo.albums[1].Price)
+
+	// Purging is for admins
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/albums/1?hard=true", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+// historyTxRepo adds an empty history to txRepo
+type historyTxRepo struct {
+	*txRepo
+}
+
+func (r *historyTxRepo) History(ctx context.Context, albumID int) ([]Revision, error) {
+	return []Revision{}, nil
+}
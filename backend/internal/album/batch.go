@@ -0,0 +1,231 @@
+package album
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"web-service-gin/backend/internal/middleware"
+)
+
+// MaxBatchSize is the largest number of operations a batch may contain
+const MaxBatchSize = 1000
+
+// Batch operation names
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// ErrInvalidBatch is returned when a batch operation is malformed
+var ErrInvalidBatch = errors.New("invalid batch")
+
+// BatchRequest is a list of operations applied in order in one transaction
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations" binding:"required"`
+}
+
+// BatchOperation creates, updates or deletes one album. Album holds the
+// fields to set: all of title, artist and price for a create, and any of
+// them for an update, which is applied like a JSON Merge Patch. Version,
+// when set, must match the album's current version as with If-Match.
+type BatchOperation struct {
+	Op      string          `json:"op"`
+	ID      int             `json:"id,omitempty"`
+	Version int             `json:"version,omitempty"`
+	Album   json.RawMessage `json:"album,omitempty"`
+
+	patch Patch
+}
+
+// BatchResult is the outcome of one operation. Status is the HTTP status the
+// operation would have had as a single request.
+type BatchResult struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	Status int    `json:"status"`
+	ID     int    `json:"id,omitempty"`
+	Album  *Album `json:"album,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchResponse reports whether a batch was committed and how each
+// operation fared. When a batch is rolled back, results stop at the
+// operation that failed and nothing they describe was saved.
+type BatchResponse struct {
+	Committed bool          `json:"committed"`
+	Results   []BatchResult `json:"results"`
+}
+
+// validate checks an operation's shape and parses its album fields, so that
+// malformed operations are reported before anything is written
+func (op *BatchOperation) validate() error {
+	switch op.Op {
+	case BatchCreate:
+		if op.ID != 0 || op.Version != 0 {
+			return fmt.Errorf("%w: create does not take an id or version", ErrInvalidBatch)
+		}
+	case BatchUpdate, BatchDelete:
+		if op.ID <= 0 {
+			return fmt.Errorf("%w: %s requires an id", ErrInvalidBatch, op.Op)
+		}
+		if op.Version < 0 {
+			return fmt.Errorf("%w: version must not be negative", ErrInvalidBatch)
+		}
+	default:
+		return fmt.Errorf("%w: op must be create, update or delete", ErrInvalidBatch)
+	}
+
+	if op.Op == BatchDelete {
+		if len(op.Album) > 0 {
+			return fmt.Errorf("%w: delete does not take an album", ErrInvalidBatch)
+		}
+		return nil
+	}
+
+	if len(op.Album) == 0 {
+		return fmt.Errorf("%w: %s requires an album", ErrInvalidBatch, op.Op)
+	}
+
+	patch, err := ParseMergePatch(op.Album)
+	if err != nil {
+		return err
+	}
+	if op.Op == BatchCreate {
+		if patch.Title == nil || patch.Artist == nil || patch.Price == nil {
+			return fmt.Errorf("%w: create requires title, artist and price", ErrInvalidBatch)
+		}
+		// Check the album up front; Apply runs again when the batch does
+		if err := patch.Apply(&Album{}); err != nil {
+			return err
+		}
+	}
+	op.patch = patch
+
+	return nil
+}
+
+// run applies the operation using the given repository
+func (op *BatchOperation) run(ctx context.Context, repo Repository) (BatchResult, error) {
+	result := BatchResult{Op: op.Op, ID: op.ID}
+
+	switch op.Op {
+	case BatchCreate:
+		var album Album
+		if err := op.patch.Apply(&album); err != nil {
+			return result, err
+		}
+		if err := AuthorizeChange(ctx, nil, &album); err != nil {
+			return result, err
+		}
+		if err := repo.Create(ctx, &album); err != nil {
+			return result, err
+		}
+		result.Status, result.ID, result.Album = http.StatusCreated, album.ID, &album
+
+	case BatchUpdate:
+		album, err := repo.FindByID(ctx, op.ID)
+		if err != nil {
+			return result, err
+		}
+		if op.Version != 0 && op.Version != album.Version {
+			return result, ErrVersionConflict
+		}
+		before := clone(album)
+		if err := op.patch.Apply(album); err != nil {
+			return result, err
+		}
+		if err := AuthorizeChange(ctx, before, album); err != nil {
+			return result, err
+		}
+		if err := repo.Update(ctx, album); err != nil {
+			return result, err
+		}
+		result.Status, result.Album = http.StatusOK, album
+
+	case BatchDelete:
+		if err := AuthorizeChange(ctx, &Album{ID: op.ID}, nil); err != nil {
+			return result, err
+		}
+		if err := repo.Delete(ctx, op.ID, op.Version); err != nil {
+			return result, err
+		}
+		result.Status = http.StatusOK
+	}
+
+	return result, nil
+}
+
+// ValidateBatch checks every operation in a batch and reports the ones that
+// are malformed. It returns nil when the batch can be run.
+func ValidateBatch(ops []BatchOperation) []BatchResult {
+	var invalid []BatchResult
+	for i := range ops {
+		if err := ops[i].validate(); err != nil {
+			invalid = append(invalid, BatchResult{
+				Index:  i,
+				Op:     ops[i].Op,
+				Status: http.StatusBadRequest,
+				ID:     ops[i].ID,
+				Error:  err.Error(),
+			})
+		}
+	}
+	return invalid
+}
+
+// RunBatch applies validated operations in order inside one transaction. If
+// an operation fails, the transaction is rolled back and the response ends
+// with that operation's result.
+func RunBatch(ctx context.Context, repo Repository, ops []BatchOperation) (BatchResponse, error) {
+	results := make([]BatchResult, 0, len(ops))
+	var failure error
+
+	err := repo.WithinTx(ctx, func(tx Repository) error {
+		for i := range ops {
+			result, err := ops[i].run(ctx, tx)
+			result.Index = i
+			if err != nil {
+				result.Status, result.Error = batchErrorStatus(err)
+				results = append(results, result)
+				failure = err
+				return err
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	if err != nil {
+		if failure == nil {
+			// The transaction itself failed, not an operation
+			return BatchResponse{}, err
+		}
+
+		for i := range results {
+			results[i].Album = nil
+		}
+		return BatchResponse{Committed: false, Results: results}, nil
+	}
+
+	return BatchResponse{Committed: true, Results: results}, nil
+}
+
+// batchErrorStatus maps an operation error to the status and message the
+// equivalent single request would respond with
+func batchErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound, "Album not found"
+	case errors.Is(err, ErrVersionConflict):
+		return http.StatusPreconditionFailed, "Album has been modified, reload it and try again"
+	case errors.Is(err, ErrInvalidPatch), errors.Is(err, ErrInvalidBatch):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, middleware.ErrForbidden):
+		return http.StatusForbidden, middleware.ForbiddenMessage(err)
+	default:
+		return http.StatusInternalServerError, "Failed to apply operation"
+	}
+}
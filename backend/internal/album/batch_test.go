@@ -0,0 +1,189 @@
+package album
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// txRepo is an in-memory Repository whose WithinTx restores the albums when
+// fn fails. Methods the tests do not exercise fall through to the nil
+// embedded interface and panic.
+type txRepo struct {
+	Repository
+	albums map[int]Album
+	nextID int
+}
+
+func newTxRepo(albums ...Album) *txRepo {
+	r := &txRepo{albums: make(map[int]Album), nextID: 1}
+	for _, a := range albums {
+		r.albums[a.ID] = a
+		if a.ID >= r.nextID {
+			r.nextID = a.ID + 1
+		}
+	}
+	return r
+}
+
+func (r *txRepo) WithinTx(ctx context.Context, fn func(repo Repository) error) error {
+	saved, nextID := make(map[int]Album, len(r.albums)), r.nextID
+	for id, a := range r.albums {
+		saved[id] = a
+	}
+
+	if err := fn(r); err != nil {
+		r.albums, r.nextID = saved, nextID
+		return err
+	}
+	return nil
+}
+
+func (r *txRepo) Create(ctx context.Context, a *Album) error {
+	a.ID, a.Version = r.nextID, 1
+	r.nextID++
+	r.albums[a.ID] = *a
+	return nil
+}
+
+func (r *txRepo) FindByID(ctx context.Context, id int) (*Album, error) {
+	a, ok := r.albums[id]
+	if !ok || a.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	return &a, nil
+}
+
+func (r *txRepo) Update(ctx context.Context, a *Album) error {
+	current, err := r.FindByID(ctx, a.ID)
+	if err != nil {
+		return err
+	}
+	if a.Version != 0 && a.Version != current.Version {
+		return ErrVersionConflict
+	}
+	a.Version = current.Version + 1
+	r.albums[a.ID] = *a
+	return nil
+}
+
+func (r *txRepo) Delete(ctx context.Context, id int, version int) error {
+	current, err := r.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if version != 0 && version != current.Version {
+		return ErrVersionConflict
+	}
+	delete(r.albums, id)
+	return nil
+}
+
+func parseBatch(t *testing.T, body string) []BatchOperation {
+	var req BatchRequest
+	require.NoError(t, json.Unmarshal([]byte(body), &req))
+	return req.Operations
+}
+
+func TestValidateBatch(t *testing.T) {
+	ops := parseBatch(t, `{"operations": [
+		{"op": "create", "album": {"title": "Blue Train", "artist": "John Coltrane", "price": 56.99}},
+		{"op": "create", "album": {"title": "Blue Train"}},
+		{"op": "update", "album": {"price": 10}},
+		{"op": "update", "id": 1, "album": {"id": 2}},
+		{"op": "delete", "id": 1, "album": {}},
+		{"op": "upsert", "id": 1},
+		{"op": "delete", "id": 1, "version": 2}
+	]}`)
+
+	invalid := ValidateBatch(ops)
+	indexes := make([]int, 0, len(invalid))
+	for _, result := range invalid {
+		assert.Equal(t, http.StatusBadRequest, result.Status)
+		assert.NotEmpty(t, result.Error)
+		indexes = append(indexes, result.Index)
+	}
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, indexes)
+}
+
+func TestRunBatch_Commits(t *testing.T) {
+	repo := newTxRepo(
+		Album{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 5699, Currency: "USD", Version: 1},
+		Album{ID: 2, Title: "Giant Steps", Artist: "John Coltrane", Price: 1799, Currency: "USD", Version: 4},
+	)
+	ops := parseBatch(t, `{"operations": [
+		{"op": "create", "album": {"title": "The Wall", "artist": "Pink Floyd", "price": 24.99}},
+		{"op": "update", "id": 1, "version": 1, "album": {"price": "49.99"}},
+		{"op": "delete", "id": 2, "version": 4}
+	]}`)
+	require.Empty(t, ValidateBatch(ops))
+
+	response, err := RunBatch(context.Background(), repo, ops)
+	require.NoError(t, err)
+	assert.True(t, response.Committed)
+	require.Len(t, response.Results, 3)
+
+	assert.Equal(t, http.StatusCreated, response.Results[0].Status)
+	assert.Equal(t, 3, response.Results[0].ID)
+	assert.Equal(t, http.StatusOK, response.Results[1].Status)
+	assert.Equal(t, Money(4999), response.Results[1].Album.Price)
+	assert.Equal(t, 2, response.Results[1].Album.Version)
+	assert.Equal(t, http.StatusOK, response.Results[2].Status)
+
+	assert.Len(t, repo.albums, 2)
+	assert.Equal(t, "The Wall", repo.albums[3].Title)
+}
+
+func TestRunBatch_RollsBack(t *testing.T) {
+	repo := newTxRepo(Album{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 5699, Currency: "USD", Version: 2})
+	ops := parseBatch(t, `{"operations": [
+		{"op": "create", "album": {"title": "The Wall", "artist": "Pink Floyd", "price": 24.99}},
+		{"op": "update", "id": 1, "version": 1, "album": {"price": 49.99}},
+		{"op": "delete", "id": 1}
+	]}`)
+	require.Empty(t, ValidateBatch(ops))
+
+	response, err := RunBatch(context.Background(), repo, ops)
+	require.NoError(t, err)
+	assert.False(t, response.Committed)
+
+	// Results stop at the failing operation and carry no albums
+	require.Len(t, response.Results, 2)
+	assert.Nil(t, response.Results[0].Album)
+	assert.Equal(t, 1, response.Results[1].Index)
+	assert.Equal(t, http.StatusPreconditionFailed, response.Results[1].Status)
+
+	assert.Len(t, repo.albums, 1)
+	assert.Equal(t, Money(5699), repo.albums[1].Price)
+}
+
+func TestHandler_BatchAlbums(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"commits", `{"operations": [{"op": "delete", "id": 1}]}`, http.StatusOK},
+		{"missing album", `{"operations": [{"op": "delete", "id": 9}]}`, http.StatusNotFound},
+		{"malformed operation", `{"operations": [{"op": "delete"}]}`, http.StatusBadRequest},
+		{"empty", `{"operations": []}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTxRepo(Album{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 5699, Version: 1})
+			req := httptest.NewRequest(http.MethodPost, "/albums/batch", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			newTestRouter(repo, Config{}).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package album
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// DefaultTrashRetention is how long soft-deleted albums are kept
+	DefaultTrashRetention = 30 * 24 * time.Hour
+	// DefaultPurgeInterval is how often expired albums are purged
+	DefaultPurgeInterval = time.Hour
+)
+
+// Config holds album settings
+type Config struct {
+	// AdminToken unlocks admin-only operations such as hard deletes.
+	// Empty disables them.
+	AdminToken string
+	// TrashRetention is how long soft-deleted albums are kept before they
+	// are purged. Zero keeps them forever.
+	TrashRetention time.Duration
+	// PurgeInterval is how often the retention job runs
+	PurgeInterval time.Duration
+}
+
+// LoadConfig reads album settings from the environment
+func LoadConfig() (Config, error) {
+	config := Config{
+		AdminToken:     os.Getenv("ADMIN_TOKEN"),
+		TrashRetention: DefaultTrashRetention,
+		PurgeInterval:  DefaultPurgeInterval,
+	}
+
+	if raw := os.Getenv("ALBUM_TRASH_RETENTION"); raw != "" {
+		retention, err := time.ParseDuration(raw)
+		if err != nil || retention < 0 {
+			return config, fmt.Errorf("ALBUM_TRASH_RETENTION must be a duration (0 disables purging), got %q", raw)
+		}
+		config.TrashRetention = retention
+	}
+
+	if raw := os.Getenv("ALBUM_PURGE_INTERVAL"); raw != "" {
+		interval, err := time.ParseDuration(raw)
+		if err != nil || interval <= 0 {
+			return config, fmt.Errorf("ALBUM_PURGE_INTERVAL must be a positive duration, got %q", raw)
+		}
+		config.PurgeInterval = interval
+	}
+
+	return config, nil
+}
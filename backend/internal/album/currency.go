@@ -0,0 +1,230 @@
+package album
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// DefaultCurrency is the currency of prices that do not name one
+const DefaultCurrency = "USD"
+
+// maxRateDecimals matches the scale of the exchange_rates.rate column
+const maxRateDecimals = 8
+
+var (
+	// ErrInvalidPricing is returned when a currency or price point is invalid
+	ErrInvalidPricing = errors.New("invalid pricing")
+	// ErrNoExchangeRate is returned when no rate converts between two currencies
+	ErrNoExchangeRate = errors.New("no exchange rate")
+)
+
+// PricePoint is an explicit price in a currency other than the album's own
+type PricePoint struct {
+	Currency string `json:"currency"`
+	Amount   Money  `json:"amount"`
+}
+
+// ConvertedPrice is an album price expressed in a requested currency.
+// Source is "base" for the album's own currency, "price_point" for an
+// explicit price point and "exchange_rate" for a conversion.
+type ConvertedPrice struct {
+	Currency        string     `json:"currency"`
+	Amount          Money      `json:"amount"`
+	Source          string     `json:"source"`
+	Rate            string     `json:"rate,omitempty"`
+	RateEffectiveAt *time.Time `json:"rate_effective_at,omitempty"`
+}
+
+// ExchangeRate converts amounts in Base into Quote from EffectiveAt until a
+// newer rate for the same pair takes effect. Rate is an exact decimal.
+type ExchangeRate struct {
+	ID          int       `json:"id"`
+	Base        string    `json:"base"`
+	Quote       string    `json:"quote"`
+	Rate        string    `json:"rate"`
+	EffectiveAt time.Time `json:"effective_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CreateRateRequest represents a request to record an exchange rate
+type CreateRateRequest struct {
+	Base        string      `json:"base" binding:"required"`
+	Quote       string      `json:"quote" binding:"required"`
+	Rate        json.Number `json:"rate" binding:"required"`
+	EffectiveAt *time.Time  `json:"effective_at"`
+}
+
+// toRate validates the request and builds the rate it describes
+func (req CreateRateRequest) toRate(now time.Time) (*ExchangeRate, error) {
+	base, err := ParseCurrency(req.Base)
+	if err != nil {
+		return nil, err
+	}
+	quote, err := ParseCurrency(req.Quote)
+	if err != nil {
+		return nil, err
+	}
+	if base == quote {
+		return nil, fmt.Errorf("%w: base and quote currency must differ", ErrInvalidPricing)
+	}
+
+	rate, err := parseRate(req.Rate.String())
+	if err != nil {
+		return nil, err
+	}
+
+	effectiveAt := now
+	if req.EffectiveAt != nil {
+		effectiveAt = *req.EffectiveAt
+	}
+
+	return &ExchangeRate{Base: base, Quote: quote, Rate: formatRate(rate), EffectiveAt: effectiveAt}, nil
+}
+
+// ParseCurrency validates and normalizes an ISO 4217 currency code
+func ParseCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return "", fmt.Errorf("%w: %q is not a three-letter currency code", ErrInvalidPricing, code)
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return "", fmt.Errorf("%w: %q is not a three-letter currency code", ErrInvalidPricing, code)
+		}
+	}
+	return code, nil
+}
+
+// NormalizePricing defaults and upper-cases currencies and checks that price
+// points are valid, unique and distinct from the album's own currency
+func (a *Album) NormalizePricing() error {
+	if a.Currency == "" {
+		a.Currency = DefaultCurrency
+	}
+
+	currency, err := ParseCurrency(a.Currency)
+	if err != nil {
+		return err
+	}
+	a.Currency = currency
+
+	seen := map[string]bool{a.Currency: true}
+	for i := range a.Prices {
+		point := &a.Prices[i]
+		if point.Currency, err = ParseCurrency(point.Currency); err != nil {
+			return err
+		}
+		if seen[point.Currency] {
+			return fmt.Errorf("%w: more than one price in %s", ErrInvalidPricing, point.Currency)
+		}
+		seen[point.Currency] = true
+
+		if err := point.Amount.Validate(); err != nil {
+			return fmt.Errorf("%w: %s price: %v", ErrInvalidPricing, point.Currency, err)
+		}
+	}
+
+	return nil
+}
+
+// parseRate parses a positive decimal exchange rate
+func parseRate(s string) (*big.Rat, error) {
+	s = strings.TrimSpace(s)
+	whole, frac, hasPoint := strings.Cut(s, ".")
+	rate, ok := new(big.Rat).SetString(s)
+	if !ok || !isDigits(whole) || (hasPoint && !isDigits(frac)) || rate.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: rate %q must be a positive decimal number", ErrInvalidPricing, s)
+	}
+
+	scaled := new(big.Rat).Mul(rate, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(maxRateDecimals), nil)))
+	if !scaled.IsInt() {
+		return nil, fmt.Errorf("%w: rate %q has more than %d decimal places", ErrInvalidPricing, s, maxRateDecimals)
+	}
+
+	return rate, nil
+}
+
+// formatRate writes a rate without trailing zeros
+func formatRate(rate *big.Rat) string {
+	s := rate.FloatString(maxRateDecimals)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// convertMoney multiplies an amount by a rate, rounding half away from zero to the cent
+func convertMoney(amount Money, rate *big.Rat) Money {
+	product := new(big.Rat).Mul(new(big.Rat).SetInt64(int64(amount)), rate)
+
+	quotient, remainder := new(big.Int).QuoRem(product.Num(), product.Denom(), new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(product.Denom()) >= 0 {
+		quotient.Add(quotient, big.NewInt(int64(product.Num().Sign())))
+	}
+
+	return Money(quotient.Int64())
+}
+
+// Converter expresses album prices in other currencies
+type Converter struct {
+	rates RateRepository
+	// cache holds the rates already looked up, keyed by "BASE/QUOTE"
+	cache map[string]*ExchangeRate
+}
+
+// NewConverter creates a converter. Rates are cached for the converter's
+// lifetime, so use one per request.
+func NewConverter(rates RateRepository) *Converter {
+	return &Converter{rates: rates, cache: make(map[string]*ExchangeRate)}
+}
+
+// Convert sets the album's converted price in the given currency, preferring
+// an explicit price point over an exchange rate effective at the given time
+func (cv *Converter) Convert(ctx context.Context, a *Album, currency string, at time.Time) error {
+	if a.Currency == currency {
+		a.Converted = &ConvertedPrice{Currency: currency, Amount: a.Price, Source: "base"}
+		return nil
+	}
+
+	for _, point := range a.Prices {
+		if point.Currency == currency {
+			a.Converted = &ConvertedPrice{Currency: currency, Amount: point.Amount, Source: "price_point"}
+			return nil
+		}
+	}
+
+	key := a.Currency + "/" + currency
+	rate, ok := cv.cache[key]
+	if !ok {
+		var err error
+		if rate, err = cv.rates.FindRate(ctx, a.Currency, currency, at); err != nil {
+			return err
+		}
+		cv.cache[key] = rate
+	}
+
+	value, err := parseRate(rate.Rate)
+	if err != nil {
+		return err
+	}
+
+	// A stored QUOTE/BASE rate converts in the other direction. The inverse
+	// is rounded to the stored precision so the reported rate reproduces
+	// the converted amount.
+	if rate.Base != a.Currency {
+		value, _ = new(big.Rat).SetString(value.Inv(value).FloatString(maxRateDecimals))
+	}
+
+	effectiveAt := rate.EffectiveAt
+	a.Converted = &ConvertedPrice{
+		Currency:        currency,
+		Amount:          convertMoney(a.Price, value),
+		Source:          "exchange_rate",
+		Rate:            formatRate(value),
+		RateEffectiveAt: &effectiveAt,
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package album
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"web-service-gin/backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubRates is an in-memory RateRepository
+type stubRates struct {
+	rates   []ExchangeRate
+	lookups int
+}
+
+func (s *stubRates) Create(ctx context.Context, rate *ExchangeRate) error {
+	rate.ID = len(s.rates) + 1
+	s.rates = append(s.rates, *rate)
+	return nil
+}
+
+func (s *stubRates) List(ctx context.Context, base, quote string) ([]ExchangeRate, error) {
+	return s.rates, nil
+}
+
+func (s *stubRates) FindRate(ctx context.Context, base, quote string, at time.Time) (*ExchangeRate, error) {
+	s.lookups++
+	var best *ExchangeRate
+	for i := range s.rates {
+		rate := &s.rates[i]
+		direct := rate.Base == base && rate.Quote == quote
+		inverse := rate.Base == quote && rate.Quote == base
+		if (!direct && !inverse) || rate.EffectiveAt.After(at) {
+			continue
+		}
+		if best == nil || (direct && best.Base != base) || (direct == (best.Base == base) && rate.EffectiveAt.After(best.EffectiveAt)) {
+			best = rate
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("%w from %s to %s", ErrNoExchangeRate, base, quote)
+	}
+	return best, nil
+}
+
+func TestParseCurrency(t *testing.T) {
+	code, err := ParseCurrency(" eur ")
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", code)
+
+	for _, input := range []string{"", "EU", "EURO", "E1R", "€UR"} {
+		_, err := ParseCurrency(input)
+		assert.ErrorIs(t, err, ErrInvalidPricing, input)
+	}
+}
+
+func TestAlbum_NormalizePricing(t *testing.T) {
+	album := &Album{Price: 1999, Prices: []PricePoint{{Currency: "eur", Amount: 1899}}}
+	require.NoError(t, album.NormalizePricing())
+	assert.Equal(t, DefaultCurrency, album.Currency)
+	assert.Equal(t, "EUR", album.Prices[0].Currency)
+
+	tests := []struct {
+		name  string
+		album Album
+	}{
+		{"invalid currency", Album{Currency: "dollars"}},
+		{"price point in own currency", Album{Currency: "EUR", Prices: []PricePoint{{Currency: "eur", Amount: 100}}}},
+		{"duplicate price point", Album{Prices: []PricePoint{{Currency: "GBP", Amount: 100}, {Currency: "gbp", Amount: 200}}}},
+		{"negative price point", Album{Prices: []PricePoint{{Currency: "GBP", Amount: -1}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.album.NormalizePricing(), ErrInvalidPricing)
+		})
+	}
+}
+
+func TestCreateRateRequest_ToRate(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	rate, err := CreateRateRequest{Base: "usd", Quote: "eur", Rate: json.Number("0.92500000")}.toRate(now)
+	require.NoError(t, err)
+	assert.Equal(t, "USD", rate.Base)
+	assert.Equal(t, "EUR", rate.Quote)
+	assert.Equal(t, "0.925", rate.Rate)
+	assert.Equal(t, now, rate.EffectiveAt)
+
+	for _, req := range []CreateRateRequest{
+		{Base: "USD", Quote: "USD", Rate: "1"},
+		{Base: "USD", Quote: "EUR", Rate: "0"},
+		{Base: "USD", Quote: "EUR", Rate: "-1.2"},
+		{Base: "USD", Quote: "EUR", Rate: "1e3"},
+		{Base: "USD", Quote: "EUR", Rate: "0.123456789"},
+	} {
+		_, err := req.toRate(now)
+		assert.ErrorIs(t, err, ErrInvalidPricing, string(req.Rate))
+	}
+}
+
+func TestConvertMoney(t *testing.T) {
+	rate, err := parseRate("0.925")
+	require.NoError(t, err)
+	assert.Equal(t, Money(1849), convertMoney(1999, rate)) // 18.49075
+
+	// Halves round away from zero
+	half, err := parseRate("0.5")
+	require.NoError(t, err)
+	assert.Equal(t, Money(1), convertMoney(1, half))
+	assert.Equal(t, Money(2), convertMoney(3, half))
+}
+
+func TestConverter_Convert(t *testing.T) {
+	ctx := context.Background()
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	rates := &stubRates{rates: []ExchangeRate{
+		{Base: "USD", Quote: "EUR", Rate: "0.9", EffectiveAt: jan},
+		{Base: "USD", Quote: "EUR", Rate: "0.8", EffectiveAt: feb},
+		{Base: "GBP", Quote: "USD", Rate: "1.25", EffectiveAt: jan},
+	}}
+
+	newAlbum := func() *Album {
+		return &Album{Price: 1000, Currency: "USD", Prices: []PricePoint{{Currency: "JPY", Amount: 150000}}}
+	}
+
+	t.Run("base currency", func(t *testing.T) {
+		album := newAlbum()
+		require.NoError(t, NewConverter(rates).Convert(ctx, album, "USD", feb))
+		assert.Equal(t, &ConvertedPrice{Currency: "USD", Amount: 1000, Source: "base"}, album.Converted)
+	})
+
+	t.Run("price point", func(t *testing.T) {
+		album := newAlbum()
+		require.NoError(t, NewConverter(rates).Convert(ctx, album, "JPY", feb))
+		assert.Equal(t, &ConvertedPrice{Currency: "JPY", Amount: 150000, Source: "price_point"}, album.Converted)
+	})
+
+	t.Run("rate in effect", func(t *testing.T) {
+		album := newAlbum()
+		require.NoError(t, NewConverter(rates).Convert(ctx, album, "EUR", jan.Add(24*time.Hour)))
+		assert.Equal(t, Money(900), album.Converted.Amount)
+		assert.Equal(t, "0.9", album.Converted.Rate)
+		assert.Equal(t, jan, *album.Converted.RateEffectiveAt)
+
+		album = newAlbum()
+		require.NoError(t, NewConverter(rates).Convert(ctx, album, "EUR", feb))
+		assert.Equal(t, Money(800), album.Converted.Amount)
+	})
+
+	t.Run("inverse rate", func(t *testing.T) {
+		album := newAlbum()
+		require.NoError(t, NewConverter(rates).Convert(ctx, album, "GBP", feb))
+		assert.Equal(t, Money(800), album.Converted.Amount)
+		assert.Equal(t, "0.8", album.Converted.Rate)
+		assert.Equal(t, "exchange_rate", album.Converted.Source)
+	})
+
+	t.Run("no rate", func(t *testing.T) {
+		album := newAlbum()
+		err := NewConverter(rates).Convert(ctx, album, "CHF", feb)
+		assert.ErrorIs(t, err, ErrNoExchangeRate)
+		assert.Nil(t, album.Converted)
+
+		// Rates only apply from their effective time
+		err = NewConverter(rates).Convert(ctx, newAlbum(), "EUR", jan.Add(-time.Hour))
+		assert.ErrorIs(t, err, ErrNoExchangeRate)
+	})
+
+	t.Run("caches lookups", func(t *testing.T) {
+		rates.lookups = 0
+		converter := NewConverter(rates)
+		for i := 0; i < 3; i++ {
+			require.NoError(t, converter.Convert(ctx, newAlbum(), "EUR", feb))
+		}
+		assert.Equal(t, 1, rates.lookups)
+	})
+}
+
+func TestHandler_CreateRatePermissions(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *middleware.Principal
+		token     string
+		want      int
+	}{
+		{"admin role", &middleware.Principal{Subject: "root", Roles: []string{middleware.RoleAdmin}}, "", http.StatusCreated},
+		{"editor role", &middleware.Principal{Subject: "sam", Roles: []string{middleware.RoleEditor}}, "", http.StatusForbidden},
+		{"write key", &middleware.Principal{Subject: "api-key:1", Scopes: middleware.Scopes, Method: middleware.AuthMethodAPIKey}, "", http.StatusForbidden},
+		{"anonymous with admin token", nil, "secret", http.StatusCreated},
+		{"anonymous", nil, "", http.StatusForbidden},
+		{"anonymous with wrong token", nil, "guess", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				if tt.principal != nil {
+					middleware.SetPrincipal(c, tt.principal)
+				}
+			})
+			rates := &stubRates{}
+			NewHandler(nil, rates, Config{AdminToken: "secret"}).RegisterRateRoutes(router.Group("/exchange-rates"))
+
+			req := httptest.NewRequest(http.MethodPost, "/exchange-rates", strings.NewReader(`{"base": "USD", "quote": "EUR", "rate": "0.925"}`))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.token != "" {
+				req.Header.Set(middleware.AdminTokenHeader, tt.token)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want, w.Code, w.Body.String())
+			if tt.want == http.StatusCreated {
+				assert.Len(t, rates.rates, 1)
+			} else {
+				assert.Empty(t, rates.rates)
+			}
+		})
+	}
+}
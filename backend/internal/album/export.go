@@ -0,0 +1,146 @@
+package album
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Export formats accepted by NewExportWriter
+const (
+	ExportCSV    = "csv"
+	ExportJSON   = "json"
+	ExportNDJSON = "ndjson"
+)
+
+// exportColumns are the CSV export columns. Price points are only included
+// in the JSON formats.
+var exportColumns = []string{"id", "title", "artist", "price", "currency", "version", "created_at", "updated_at", "deleted_at"}
+
+// ExportWriter writes albums one at a time in an export format. Close must be
+// called once every album has been written.
+type ExportWriter interface {
+	Write(album *Album) error
+	Close() error
+}
+
+// ExportContentType returns the media type of an export format
+func ExportContentType(format string) string {
+	switch format {
+	case ExportCSV:
+		return "text/csv; charset=utf-8"
+	case ExportNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/json; charset=utf-8"
+	}
+}
+
+// NewExportWriter creates a writer for the given format
+func NewExportWriter(w io.Writer, format string) (ExportWriter, error) {
+	switch format {
+	case ExportCSV:
+		return &csvExportWriter{w: csv.NewWriter(w)}, nil
+	case ExportJSON:
+		return &jsonExportWriter{w: w}, nil
+	case ExportNDJSON:
+		return &ndjsonExportWriter{enc: json.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("%w: format must be csv, json or ndjson", ErrInvalidQuery)
+	}
+}
+
+// csvExportWriter writes a header row followed by one row per album
+type csvExportWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func (e *csvExportWriter) header() error {
+	if e.wroteHeader {
+		return nil
+	}
+	e.wroteHeader = true
+	return e.w.Write(exportColumns)
+}
+
+func (e *csvExportWriter) Write(album *Album) error {
+	if err := e.header(); err != nil {
+		return err
+	}
+
+	deletedAt := ""
+	if album.DeletedAt != nil {
+		deletedAt = album.DeletedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	return e.w.Write([]string{
+		strconv.Itoa(album.ID),
+		album.Title,
+		album.Artist,
+		album.Price.String(),
+		album.Currency,
+		strconv.Itoa(album.Version),
+		album.CreatedAt.UTC().Format(time.RFC3339Nano),
+		album.UpdatedAt.UTC().Format(time.RFC3339Nano),
+		deletedAt,
+	})
+}
+
+func (e *csvExportWriter) Close() error {
+	if err := e.header(); err != nil {
+		return err
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// jsonExportWriter writes a single JSON array, one element at a time
+type jsonExportWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonExportWriter) Write(album *Album) error {
+	data, err := json.Marshal(album)
+	if err != nil {
+		return err
+	}
+
+	separator := ",\n"
+	if e.count == 0 {
+		separator = "[\n"
+	}
+	e.count++
+
+	if _, err := io.WriteString(e.w, separator); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonExportWriter) Close() error {
+	closing := "\n]\n"
+	if e.count == 0 {
+		closing = "[]\n"
+	}
+	_, err := io.WriteString(e.w, closing)
+	return err
+}
+
+// ndjsonExportWriter writes one JSON object per line
+type ndjsonExportWriter struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonExportWriter) Write(album *Album) error {
+	return e.enc.Encode(album)
+}
+
+func (e *ndjsonExportWriter) Close() error {
+	return nil
+}
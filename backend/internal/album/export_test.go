@@ -0,0 +1,63 @@
+package album
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportAll(t *testing.T, format string, albums []Album) string {
+	var buf bytes.Buffer
+	writer, err := NewExportWriter(&buf, format)
+	require.NoError(t, err)
+	for i := range albums {
+		require.NoError(t, writer.Write(&albums[i]))
+	}
+	require.NoError(t, writer.Close())
+	return buf.String()
+}
+
+func TestExportWriter(t *testing.T) {
+	created := time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC)
+	albums := []Album{
+		{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 5699, Currency: "USD", Version: 1, CreatedAt: created, UpdatedAt: created},
+		{ID: 2, Title: "Kind of Blue, Remastered", Artist: "Miles Davis", Price: 1999, Currency: "USD", Version: 3,
+			Prices: []PricePoint{{Currency: "EUR", Amount: 1899}}, CreatedAt: created, UpdatedAt: created, DeletedAt: &created},
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		assert.Equal(t,
+			"id,title,artist,price,currency,version,created_at,updated_at,deleted_at\n"+
+				"1,Blue Train,John Coltrane,56.99,USD,1,2025-10-15T12:00:00Z,2025-10-15T12:00:00Z,\n"+
+				"2,\"Kind of Blue, Remastered\",Miles Davis,19.99,USD,3,2025-10-15T12:00:00Z,2025-10-15T12:00:00Z,2025-10-15T12:00:00Z\n",
+			exportAll(t, ExportCSV, albums))
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var decoded []Album
+		require.NoError(t, json.Unmarshal([]byte(exportAll(t, ExportJSON, albums)), &decoded))
+		require.Len(t, decoded, 2)
+		assert.Equal(t, "Kind of Blue, Remastered", decoded[1].Title)
+		assert.Equal(t, []PricePoint{{Currency: "EUR", Amount: 1899}}, decoded[1].Prices)
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		lines := bytes.Split(bytes.TrimSpace([]byte(exportAll(t, ExportNDJSON, albums))), []byte("\n"))
+		require.Len(t, lines, 2)
+		var decoded Album
+		require.NoError(t, json.Unmarshal(lines[0], &decoded))
+		assert.Equal(t, Money(5699), decoded.Price)
+	})
+
+	// Empty exports are still well-formed
+	assert.Equal(t, "id,title,artist,price,currency,version,created_at,updated_at,deleted_at\n", exportAll(t, ExportCSV, nil))
+	assert.Equal(t, "[]\n", exportAll(t, ExportJSON, nil))
+	assert.Equal(t, "", exportAll(t, ExportNDJSON, nil))
+
+	_, err := NewExportWriter(&bytes.Buffer{}, "xlsx")
+	assert.ErrorIs(t, err, ErrInvalidQuery)
+}
@@ -2,40 +2,169 @@ package album
 
 import (
 	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
+
+	"web-service-gin/backend/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
+// maxImportSize caps the body of an import request
+const maxImportSize = 32 << 20
+
 // Handler handles album HTTP requests
 type Handler struct {
-	repo Repository
+	repo   Repository
+	rates  RateRepository
+	config Config
 }
 
 // NewHandler creates a new album handler
-func NewHandler(repo Repository) *Handler {
-	return &Handler{repo: repo}
+func NewHandler(repo Repository, rates RateRepository, config Config) *Handler {
+	return &Handler{repo: repo, rates: rates, config: config}
 }
 
 // RegisterRoutes registers album routes
 func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
-	router.GET("", h.GetAlbums)
-	router.GET("/:id", h.GetAlbum)
-	router.POST("", h.CreateAlbum)
+	router.Use(TrackActor(SourceREST))
+
+	read := middleware.RequirePermission(middleware.PermAlbumsRead)
+	create := middleware.RequirePermission(middleware.PermAlbumsCreate)
+	remove := middleware.RequirePermission(middleware.PermAlbumsDelete)
+	// A revision may hold any field, so reverting needs both kinds of edit
+	revert := middleware.RequirePermission(middleware.PermAlbumsUpdate, middleware.PermAlbumsReprice)
+
+	// Updates, deletes and batches are authorized by the handlers, which
+	// know what is being changed
+	router.GET("", read, h.GetAlbums)
+	router.GET("/trash", read, h.GetTrash)
+	router.GET("/export", read, h.ExportAlbums)
+	router.GET("/:id", read, h.GetAlbum)
+	router.POST("", create, h.CreateAlbum)
+	router.POST("/import", create, h.ImportAlbums)
+	router.POST("/batch", h.BatchAlbums)
 	router.PUT("/:id", h.UpdateAlbum)
+	router.PATCH("/:id", h.PatchAlbum)
 	router.DELETE("/:id", h.DeleteAlbum)
+	router.POST("/:id/restore", remove, h.RestoreAlbum)
+	router.GET("/:id/history", read, h.GetHistory)
+	router.POST("/:id/revert", revert, h.RevertAlbum)
+}
+
+// RegisterRateRoutes registers exchange rate routes
+func (h *Handler) RegisterRateRoutes(router *gin.RouterGroup) {
+	router.GET("", middleware.RequirePermission(middleware.PermAlbumsRead), h.GetRates)
+	router.POST("", middleware.RequirePermission(middleware.PermRatesManage), h.CreateRate)
 }
 
-// GetAlbums retrieves all albums
+// GetAlbums retrieves a page of albums, optionally filtered and sorted
 func (h *Handler) GetAlbums(c *gin.Context) {
-	albums, err := h.repo.FindAll(c.Request.Context())
+	h.listAlbums(c, false)
+}
+
+// GetTrash retrieves a page of soft-deleted albums, with the same query
+// parameters as GetAlbums
+func (h *Handler) GetTrash(c *gin.Context) {
+	h.listAlbums(c, true)
+}
+
+func (h *Handler) listAlbums(c *gin.Context, deleted bool) {
+	opts, err := parseListOptions(c)
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	opts.Deleted = deleted
+
+	result, err := h.repo.List(c.Request.Context(), opts)
+	if err != nil {
+		if errors.Is(err, ErrInvalidQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve albums"})
 		return
 	}
 
-	c.JSON(http.StatusOK, albums)
+	if !h.convertPrices(c, result.Albums) {
+		return
+	}
+
+	c.JSON(http.StatusOK, ListResponse{
+		Albums:     result.Albums,
+		Total:      result.Total,
+		Limit:      result.Limit,
+		Offset:     opts.Offset,
+		NextCursor: result.NextCursor,
+		PrevCursor: result.PrevCursor,
+		Links:      buildLinks(c.Request.URL, opts, result),
+	})
+}
+
+// ExportAlbums streams every album matching the list filters as CSV, NDJSON
+// or a JSON array, in the requested sort order. Soft-deleted albums are only
+// included with ?include_deleted=true.
+func (h *Handler) ExportAlbums(c *gin.Context) {
+	opts, err := parseListOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if opts.IncludeDeleted, err = strconv.ParseBool(c.DefaultQuery("include_deleted", "false")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "include_deleted must be true or false"})
+		return
+	}
+
+	format := c.DefaultQuery("format", ExportCSV)
+	writer, err := NewExportWriter(c.Writer, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Headers are sent with the first row so that a query that fails up
+	// front can still be reported as an error
+	started := false
+	start := func() {
+		started = true
+		c.Header("Content-Type", ExportContentType(format))
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="albums.%s"`, format))
+		c.Status(http.StatusOK)
+	}
+
+	err = h.repo.Export(c.Request.Context(), opts, func(album *Album) error {
+		if !started {
+			start()
+		}
+		return writer.Write(album)
+	})
+	if err != nil {
+		if started {
+			// The status has been sent; all we can do is cut the body short
+			log.Printf("Album export failed: %v", err)
+			c.Abort()
+			return
+		}
+		if errors.Is(err, ErrInvalidQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export albums"})
+		return
+	}
+
+	if !started {
+		start()
+	}
+	if err := writer.Close(); err != nil {
+		log.Printf("Album export failed: %v", err)
+	}
 }
 
 // GetAlbum retrieves a single album by ID
@@ -58,14 +187,26 @@ func (h *Handler) GetAlbum(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, album)
+	albums := []Album{*album}
+	if !h.convertPrices(c, albums) {
+		return
+	}
+
+	c.Header("ETag", etag(album.Version))
+	c.JSON(http.StatusOK, albums[0])
 }
 
 // CreateAlbum creates a new album
 func (h *Handler) CreateAlbum(c *gin.Context) {
-	var album Album
+	var req albumRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if err := c.ShouldBindJSON(&album); err != nil {
+	album := Album{Title: req.Title, Artist: req.Artist, Price: *req.Price, Currency: req.Currency, Prices: req.Prices}
+	if err := album.NormalizePricing(); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -75,10 +216,105 @@ func (h *Handler) CreateAlbum(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", etag(album.Version))
 	c.JSON(http.StatusCreated, album)
 }
 
-// UpdateAlbum updates an existing album
+// ImportAlbums bulk-creates albums from CSV, a JSON array or NDJSON. Every
+// row is validated first and nothing is inserted unless all rows are valid.
+// With ?dry_run=true the rows are only validated.
+func (h *Handler) ImportAlbums(c *gin.Context) {
+	format, err := ImportFormat(c.Query("format"), c.ContentType())
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return
+	}
+
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be true or false"})
+		return
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	rows, err := ParseImport(body, format)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Import must be at most %d bytes", maxImportSize)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := ImportResult{DryRun: dryRun, Total: len(rows), Errors: make([]ImportRowError, 0)}
+	albums := make([]Album, 0, len(rows))
+	for _, row := range rows {
+		if len(row.Errors) > 0 {
+			result.Errors = append(result.Errors, ImportRowError{Row: row.Row, Errors: row.Errors})
+			continue
+		}
+		albums = append(albums, row.Album)
+	}
+	result.Valid = len(albums)
+
+	if len(result.Errors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, result)
+		return
+	}
+	if dryRun || len(albums) == 0 {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	result.Imported, err = h.repo.Import(c.Request.Context(), albums)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import albums"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// BatchAlbums applies a list of create, update and delete operations in one
+// transaction: either every operation takes effect or none do. The response
+// has the status of the failing operation when the batch is rolled back.
+func (h *Handler) BatchAlbums(c *gin.Context) {
+	var req BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(req.Operations) == 0 || len(req.Operations) > MaxBatchSize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("A batch must have between 1 and %d operations", MaxBatchSize),
+		})
+		return
+	}
+
+	if invalid := ValidateBatch(req.Operations); len(invalid) > 0 {
+		c.JSON(http.StatusBadRequest, BatchResponse{Committed: false, Results: invalid})
+		return
+	}
+
+	response, err := RunBatch(c.Request.Context(), h.repo, req.Operations)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply batch"})
+		return
+	}
+
+	if !response.Committed {
+		c.JSON(response.Results[len(response.Results)-1].Status, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateAlbum updates an existing album. An If-Match header must match the
+// album's current ETag; without one the request gets 428.
 func (h *Handler) UpdateAlbum(c *gin.Context) {
 	// Validate and parse ID
 	idParam := c.Param("id")
@@ -88,6 +324,10 @@ func (h *Handler) UpdateAlbum(c *gin.Context) {
 		return
 	}
 
+	if !requireIfMatch(c) {
+		return
+	}
+
 	// Check if album exists
 	album, err := h.repo.FindByID(c.Request.Context(), id)
 	if err != nil {
@@ -99,27 +339,145 @@ func (h *Handler) UpdateAlbum(c *gin.Context) {
 		return
 	}
 
+	if !ifMatch(c, album) {
+		preconditionFailed(c, album.Version)
+		return
+	}
+	before := clone(album)
+
 	// Bind the updated data
-	var updatedAlbum Album
-	if err := c.ShouldBindJSON(&updatedAlbum); err != nil {
+	var req albumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Update the album fields. Currency and price points are kept when omitted.
+	album.Title = req.Title
+	album.Artist = req.Artist
+	album.Price = *req.Price
+	if req.Currency != "" {
+		album.Currency = req.Currency
+	}
+	if req.Prices != nil {
+		album.Prices = req.Prices
+	}
+
+	if err := album.NormalizePricing(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := AuthorizeChange(c.Request.Context(), before, album); err != nil {
+		middleware.Forbidden(c, err)
+		return
+	}
+
+	if err := h.repo.Update(c.Request.Context(), album); err != nil {
+		switch {
+		case errors.Is(err, ErrVersionConflict):
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Album has been modified, reload it and try again"})
+		case errors.Is(err, ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update album"})
+		}
+		return
+	}
+
+	c.Header("ETag", etag(album.Version))
+	c.JSON(http.StatusOK, album)
+}
+
+// PatchAlbum partially updates an album. The body is a JSON Merge Patch, or a
+// JSON Patch when sent as application/json-patch+json. An If-Match header
+// must match the album's current ETag; without one the request gets 428.
+func (h *Handler) PatchAlbum(c *gin.Context) {
+	// Validate and parse ID
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid album ID"})
+		return
+	}
+
+	if !requireIfMatch(c) {
+		return
+	}
+
+	contentType := c.ContentType()
+	if contentType != MergePatchContentType && contentType != JSONPatchContentType && contentType != "application/json" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error": fmt.Sprintf("Content-Type must be %s or %s", MergePatchContentType, JSONPatchContentType),
+		})
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+
+	album, err := h.repo.FindByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve album"})
+		return
+	}
+
+	if !ifMatch(c, album) {
+		preconditionFailed(c, album.Version)
+		return
+	}
+	before := clone(album)
+
+	var patch Patch
+	if contentType == JSONPatchContentType {
+		patch, err = ParseJSONPatch(body, album)
+	} else {
+		patch, err = ParseMergePatch(body)
+	}
+	if err == nil {
+		err = patch.Apply(album)
+	}
+	if err != nil {
+		if errors.Is(err, ErrPatchTestFailed) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Update the album fields
-	album.Title = updatedAlbum.Title
-	album.Artist = updatedAlbum.Artist
-	album.Price = updatedAlbum.Price
+	if err := AuthorizeChange(c.Request.Context(), before, album); err != nil {
+		middleware.Forbidden(c, err)
+		return
+	}
 
 	if err := h.repo.Update(c.Request.Context(), album); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update album"})
+		switch {
+		case errors.Is(err, ErrVersionConflict):
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Album has been modified, reload it and try again"})
+		case errors.Is(err, ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update album"})
+		}
 		return
 	}
 
+	c.Header("ETag", etag(album.Version))
 	c.JSON(http.StatusOK, album)
 }
 
-// DeleteAlbum deletes an album by ID
+// DeleteAlbum moves an album to the trash. An If-Match header must match the
+// album's current ETag; without one the request gets 428. With ?hard=true an
+// admin removes the album permanently, including from the trash, and no
+// If-Match is needed.
 func (h *Handler) DeleteAlbum(c *gin.Context) {
 	// Validate and parse ID
 	idParam := c.Param("id")
@@ -129,8 +487,61 @@ func (h *Handler) DeleteAlbum(c *gin.Context) {
 		return
 	}
 
+	if hard, _ := strconv.ParseBool(c.Query("hard")); hard {
+		h.purgeAlbum(c, id)
+		return
+	}
+
+	if err := AuthorizeChange(c.Request.Context(), &Album{ID: id}, nil); err != nil {
+		middleware.Forbidden(c, err)
+		return
+	}
+
+	if !requireIfMatch(c) {
+		return
+	}
+
+	album, err := h.repo.FindByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve album"})
+		return
+	}
+
+	if !ifMatch(c, album) {
+		preconditionFailed(c, album.Version)
+		return
+	}
+
 	// Delete the album
-	if err := h.repo.Delete(c.Request.Context(), id); err != nil {
+	if err := h.repo.Delete(c.Request.Context(), id, album.Version); err != nil {
+		switch {
+		case errors.Is(err, ErrVersionConflict):
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Album has been modified, reload it and try again"})
+		case errors.Is(err, ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete album"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Album deleted successfully"})
+}
+
+// purgeAlbum permanently deletes an album. It takes the admin token or a
+// caller with the purge permission.
+func (h *Handler) purgeAlbum(c *gin.Context, id int) {
+	principal, ok := middleware.PrincipalFrom(c)
+	if !middleware.HasAdminToken(c, h.config.AdminToken) && !(ok && principal.Can(middleware.PermAlbumsPurge)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permanent deletion requires an admin token or the albums.purge permission"})
+		return
+	}
+
+	if err := h.repo.Purge(c.Request.Context(), id); err != nil {
 		if errors.Is(err, ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
 			return
@@ -139,5 +550,332 @@ func (h *Handler) DeleteAlbum(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Album deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Album permanently deleted"})
+}
+
+// RestoreAlbum moves a soft-deleted album out of the trash
+func (h *Handler) RestoreAlbum(c *gin.Context) {
+	// Validate and parse ID
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid album ID"})
+		return
+	}
+
+	album, err := h.repo.Restore(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found in trash"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore album"})
+		return
+	}
+
+	c.Header("ETag", etag(album.Version))
+	c.JSON(http.StatusOK, album)
+}
+
+// GetHistory lists the recorded changes to an album, newest first. History
+// is kept for albums in the trash and albums that have been purged.
+func (h *Handler) GetHistory(c *gin.Context) {
+	// Validate and parse ID
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid album ID"})
+		return
+	}
+
+	revisions, err := h.repo.History(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve album history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"album_id": id, "revisions": revisions})
+}
+
+// RevertAlbum sets an album's fields back to how they were after one of its
+// revisions. An If-Match header must match the album's current ETag.
+func (h *Handler) RevertAlbum(c *gin.Context) {
+	// Validate and parse ID
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid album ID"})
+		return
+	}
+
+	var req RevertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	current, err := h.repo.FindByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve album"})
+		return
+	}
+
+	if !ifMatch(c, current) {
+		preconditionFailed(c, current.Version)
+		return
+	}
+
+	album, err := h.repo.Revert(c.Request.Context(), id, req.RevisionID, current.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRevisionNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		case errors.Is(err, ErrVersionConflict):
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Album has been modified, reload it and try again"})
+		case errors.Is(err, ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revert album"})
+		}
+		return
+	}
+
+	c.Header("ETag", etag(album.Version))
+	c.JSON(http.StatusOK, album)
+}
+
+// GetRates lists exchange rates, optionally filtered by ?base= and ?quote=
+func (h *Handler) GetRates(c *gin.Context) {
+	var pair [2]string
+	for i, key := range []string{"base", "quote"} {
+		if raw := c.Query(key); raw != "" {
+			currency, err := ParseCurrency(raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			pair[i] = currency
+		}
+	}
+
+	rates, err := h.rates.List(c.Request.Context(), pair[0], pair[1])
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve exchange rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// CreateRate records an exchange rate. Callers need the rates.manage
+// permission; anonymous requests, let through when authentication is off,
+// need the admin token instead. Without effective_at the rate takes effect
+// immediately.
+func (h *Handler) CreateRate(c *gin.Context) {
+	if _, ok := middleware.PrincipalFrom(c); !ok && !middleware.HasAdminToken(c, h.config.AdminToken) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Managing exchange rates requires the rates.manage permission or an admin token"})
+		return
+	}
+
+	var req CreateRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rate, err := req.toRate(time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.rates.Create(c.Request.Context(), rate); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create exchange rate"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rate)
+}
+
+// convertPrices adds prices in the currency requested with ?currency= and
+// reports whether the response can go ahead
+func (h *Handler) convertPrices(c *gin.Context, albums []Album) bool {
+	raw := c.Query("currency")
+	if raw == "" {
+		return true
+	}
+
+	currency, err := ParseCurrency(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	converter := NewConverter(h.rates)
+	now := time.Now()
+	for i := range albums {
+		if err := converter.Convert(c.Request.Context(), &albums[i], currency, now); err != nil {
+			if errors.Is(err, ErrNoExchangeRate) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return false
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to convert prices"})
+			return false
+		}
+	}
+
+	return true
+}
+
+// etag returns the entity tag of an album version
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ifMatch reports whether the request's If-Match header, if any, matches the
+// album's current ETag. Weak tags never match.
+func ifMatch(c *gin.Context, album *Album) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return true
+	}
+
+	current := etag(album.Version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+
+	return false
+}
+
+// requireIfMatch answers 428 when the request has no If-Match header, so
+// writes cannot silently overwrite a change made since the client's read.
+// It reports whether the request can go ahead.
+func requireIfMatch(c *gin.Context) bool {
+	if c.GetHeader("If-Match") != "" {
+		return true
+	}
+	c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header is required; send the album's ETag, or * to overwrite any version"})
+	return false
+}
+
+// preconditionFailed reports a stale If-Match along with the current ETag
+func preconditionFailed(c *gin.Context, version int) {
+	c.Header("ETag", etag(version))
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Album has been modified, reload it and try again"})
+}
+
+// parseListOptions reads pagination, filter and sort query parameters.
+// A leading "-" on the sort field requests descending order.
+func parseListOptions(c *gin.Context) (ListOptions, error) {
+	opts := ListOptions{
+		Cursor:        c.Query("cursor"),
+		Search:        c.Query("q"),
+		Artist:        c.Query("artist"),
+		TitleContains: c.Query("title"),
+		PriceCurrency: c.Query("currency"),
+	}
+
+	var err error
+	if opts.Limit, err = queryInt(c, "limit"); err != nil {
+		return opts, err
+	}
+	if opts.Offset, err = queryInt(c, "offset"); err != nil {
+		return opts, err
+	}
+	if opts.MinPrice, err = queryMoney(c, "min_price"); err != nil {
+		return opts, err
+	}
+	if opts.MaxPrice, err = queryMoney(c, "max_price"); err != nil {
+		return opts, err
+	}
+
+	sort := c.Query("sort")
+	if strings.HasPrefix(sort, "-") {
+		opts.Desc = true
+		sort = strings.TrimPrefix(sort, "-")
+	}
+	opts.Sort = sort
+
+	return opts, nil
+}
+
+// queryInt parses an optional integer query parameter
+func queryInt(c *gin.Context, key string) (int, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return 0, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", key)
+	}
+
+	return value, nil
+}
+
+// queryMoney parses an optional amount query parameter
+func queryMoney(c *gin.Context, key string) (*Money, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+
+	value, err := ParseMoney(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an amount with at most two decimal places", key)
+	}
+
+	return &value, nil
+}
+
+// buildLinks derives next/prev links from the current request URL.
+// Cursor requests page by cursor; everything else pages by offset.
+func buildLinks(current *url.URL, opts ListOptions, result *ListResult) Links {
+	link := func(set map[string]string) string {
+		u := *current
+		q := u.Query()
+		for key, value := range set {
+			if value == "" {
+				q.Del(key)
+			} else {
+				q.Set(key, value)
+			}
+		}
+		u.RawQuery = q.Encode()
+		return u.RequestURI()
+	}
+
+	links := Links{Self: current.RequestURI()}
+
+	if opts.Cursor != "" {
+		if result.NextCursor != "" {
+			links.Next = link(map[string]string{"cursor": result.NextCursor})
+		}
+		if result.PrevCursor != "" {
+			links.Prev = link(map[string]string{"cursor": result.PrevCursor})
+		}
+		return links
+	}
+
+	if opts.Offset+result.Limit < result.Total {
+		links.Next = link(map[string]string{"offset": strconv.Itoa(opts.Offset + result.Limit)})
+	}
+	if opts.Offset > 0 {
+		prev := opts.Offset - result.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		links.Prev = link(map[string]string{"offset": strconv.Itoa(prev)})
+	}
+
+	return links
 }
@@ -0,0 +1,306 @@
+package album
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"web-service-gin/backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubRepo records purges, imports and export options. Methods the tests do
+// not exercise fall through to the nil embedded interface and panic.
+type stubRepo struct {
+	Repository
+	albums     []Album
+	purged     []int
+	imported   []Album
+	exportOpts ListOptions
+}
+
+func (r *stubRepo) Purge(ctx context.Context, id int) error {
+	r.purged = append(r.purged, id)
+	return nil
+}
+
+func (r *stubRepo) Import(ctx context.Context, albums []Album) (int64, error) {
+	r.imported = append(r.imported, albums...)
+	return int64(len(albums)), nil
+}
+
+func (r *stubRepo) Export(ctx context.Context, opts ListOptions, fn func(*Album) error) error {
+	r.exportOpts = opts
+	if err := opts.normalize(); err != nil {
+		return err
+	}
+	for i := range r.albums {
+		if err := fn(&r.albums[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newTestRouter(repo Repository, config Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewHandler(repo, nil, config).RegisterRoutes(router.Group("/albums"))
+	return router
+}
+
+func TestHandler_HardDeleteRequiresAdmin(t *testing.T) {
+	repo := &stubRepo{}
+	router := newTestRouter(repo, Config{AdminToken: "secret"})
+
+	tests := []struct {
+		token string
+		want  int
+	}{
+		{"", http.StatusForbidden},
+		{"wrong", http.StatusForbidden},
+		{"secret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodDelete, "/albums/7?hard=true", nil)
+		if tt.token != "" {
+			req.Header.Set("X-Admin-Token", tt.token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, tt.want, w.Code, "token %q", tt.token)
+	}
+	assert.Equal(t, []int{7}, repo.purged)
+
+	// Without a configured token nobody is an admin
+	req := httptest.NewRequest(http.MethodDelete, "/albums/7?hard=true", nil)
+	w := httptest.NewRecorder()
+	newTestRouter(repo, Config{}).ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestHandler_ImportAlbums(t *testing.T) {
+	valid := "title,artist,price\nBlue Train,John Coltrane,56.99\nGiant Steps,John Coltrane,17.99\n"
+	invalid := "title,artist,price\nBlue Train,John Coltrane,56.99\n,John Coltrane,-1\n"
+
+	tests := []struct {
+		name     string
+		target   string
+		body     string
+		want     int
+		imported int
+	}{
+		{"imports valid rows", "/albums/import", valid, http.StatusCreated, 2},
+		{"dry run", "/albums/import?dry_run=true", valid, http.StatusOK, 0},
+		{"any invalid row rejects the import", "/albums/import", invalid, http.StatusUnprocessableEntity, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &stubRepo{}
+			req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "text/csv")
+			w := httptest.NewRecorder()
+			newTestRouter(repo, Config{}).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want, w.Code)
+			assert.Len(t, repo.imported, tt.imported)
+
+			var result ImportResult
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+			assert.Equal(t, 2, result.Total)
+			assert.Equal(t, int64(tt.imported), result.Imported)
+		})
+	}
+
+	// The error report names the failing row
+	req := httptest.NewRequest(http.MethodPost, "/albums/import", strings.NewReader(invalid))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	newTestRouter(&stubRepo{}, Config{}).ServeHTTP(w, req)
+
+	var result ImportResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, 2, result.Errors[0].Row)
+	assert.Len(t, result.Errors[0].Errors, 2)
+
+	// Unknown formats are rejected before reading the body
+	req = httptest.NewRequest(http.MethodPost, "/albums/import", strings.NewReader(valid))
+	req.Header.Set("Content-Type", "application/xml")
+	w = httptest.NewRecorder()
+	newTestRouter(&stubRepo{}, Config{}).ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
+
+func TestHandler_ImportAlbums_Bulk(t *testing.T) {
+	const n = 5000
+	var body strings.Builder
+	body.WriteString("title,artist,price,currency\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&body, "Album %d,Artist %d,%d.99,EUR\n", i, i%50, i%100)
+	}
+
+	repo := &stubRepo{}
+	req := httptest.NewRequest(http.MethodPost, "/albums/import", strings.NewReader(body.String()))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	newTestRouter(repo, Config{}).ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	require.Len(t, repo.imported, n)
+	assert.Equal(t, "Album 4999", repo.imported[n-1].Title)
+	assert.Equal(t, Money(9999), repo.imported[n-1].Price)
+	assert.Equal(t, "EUR", repo.imported[n-1].Currency)
+}
+
+func TestHandler_ExportAlbums(t *testing.T) {
+	repo := &stubRepo{albums: []Album{
+		{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 5699, Currency: "USD"},
+		{ID: 2, Title: "Giant Steps", Artist: "John Coltrane", Price: 1799, Currency: "USD"},
+	}}
+	router := newTestRouter(repo, Config{})
+
+	req := httptest.NewRequest(http.MethodGet, "/albums/export?format=ndjson&artist=john%20coltrane&sort=-price&include_deleted=true", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="albums.ndjson"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, 2, strings.Count(w.Body.String(), "\n"))
+
+	// The list filters carry over
+	assert.Equal(t, "john coltrane", repo.exportOpts.Artist)
+	assert.Equal(t, "price", repo.exportOpts.Sort)
+	assert.True(t, repo.exportOpts.Desc)
+	assert.True(t, repo.exportOpts.IncludeDeleted)
+
+	// CSV is the default and soft-deleted albums are excluded unless asked for
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/albums/export", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.HasPrefix(w.Body.String(), "id,title,artist,price"))
+	assert.False(t, repo.exportOpts.IncludeDeleted)
+
+	for _, target := range []string{"/albums/export?format=xlsx", "/albums/export?sort=label"} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+}
+
+func TestHandler_IfMatchRequired(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		body   string
+	}{
+		{"put", http.MethodPut, `{"title": "Blue Train", "artist": "John Coltrane", "price": 9.99}`},
+		{"patch", http.MethodPatch, `{"price": 9.99}`},
+		{"delete", http.MethodDelete, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTxRepo(Album{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 5699, Currency: "USD", Version: 1})
+			router := newRoleRouter(repo, middleware.RoleAdmin)
+			send := func(ifMatch string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(tt.method, "/albums/1", strings.NewReader(tt.body))
+				req.Header.Set("Content-Type", "application/json")
+				if ifMatch != "" {
+					req.Header.Set("If-Match", ifMatch)
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				return w
+			}
+
+			// Without If-Match nothing is written
+			w := send("")
+			assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+			assert.Contains(t, w.Body.String(), "If-Match")
+			assert.Equal(t, 1, repo.albums[1].Version)
+			assert.Nil(t, repo.albums[1].DeletedAt)
+
+			// A stale ETag is still rejected and the current one goes through
+			assert.Equal(t, http.StatusPreconditionFailed, send(`"0"`).Code)
+			assert.Equal(t, http.StatusOK, send(`"1"`).Code)
+		})
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	album := &Album{ID: 1, Version: 3}
+
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", true},
+		{`"3"`, true},
+		{"*", true},
+		{`"2", "3"`, true},
+		{`"2"`, false},
+		{`W/"3"`, false},
+		{"3", false},
+	}
+
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPut, "/albums/1", nil)
+		if tt.header != "" {
+			c.Request.Header.Set("If-Match", tt.header)
+		}
+
+		assert.Equal(t, tt.want, ifMatch(c, album), "If-Match: %s", tt.header)
+	}
+}
+
+func TestHandler_ExplicitZeroPrice(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"update to free", http.MethodPut, "/albums/1", `{"title": "Blue Train", "artist": "John Coltrane", "price": 0}`, http.StatusOK},
+		{"create free", http.MethodPost, "/albums", `{"title": "Giant Steps", "artist": "John Coltrane", "price": 0}`, http.StatusCreated},
+		{"update without price", http.MethodPut, "/albums/1", `{"title": "Blue Train", "artist": "John Coltrane"}`, http.StatusBadRequest},
+		{"update with null price", http.MethodPut, "/albums/1", `{"title": "Blue Train", "artist": "John Coltrane", "price": null}`, http.StatusBadRequest},
+		{"create without price", http.MethodPost, "/albums", `{"title": "Giant Steps", "artist": "John Coltrane"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTxRepo(Album{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 5699, Currency: "USD", Version: 1})
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("If-Match", `"1"`)
+			w := httptest.NewRecorder()
+			newRoleRouter(repo, middleware.RoleEditor).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want, w.Code, w.Body.String())
+			switch tt.want {
+			case http.StatusOK:
+				assert.Equal(t, Money(0), repo.albums[1].Price)
+			case http.StatusCreated:
+				assert.Equal(t, Money(0), repo.albums[2].Price)
+			default:
+				assert.Equal(t, Money(5699), repo.albums[1].Price)
+				assert.Len(t, repo.albums, 1)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package album
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Import formats accepted by ParseImport
+const (
+	ImportCSV    = "csv"
+	ImportJSON   = "json"
+	ImportNDJSON = "ndjson"
+)
+
+// ErrInvalidImport is returned when an import body cannot be read at all, as
+// opposed to individual rows that fail validation
+var ErrInvalidImport = errors.New("invalid import")
+
+// importColumns are the CSV columns, in any order. Only currency is optional.
+var importColumns = []string{"title", "artist", "price", "currency"}
+
+// ImportRow is one parsed record of an import. Row counts records from 1,
+// not counting the CSV header or blank NDJSON lines.
+type ImportRow struct {
+	Row    int
+	Album  Album
+	Errors []string
+}
+
+// ImportRowError reports why a row was rejected
+type ImportRowError struct {
+	Row    int      `json:"row"`
+	Errors []string `json:"errors"`
+}
+
+// ImportResult summarizes an import. Imported is zero for a dry run or when
+// any row is invalid, since rows are only inserted when all of them are valid.
+type ImportResult struct {
+	DryRun   bool             `json:"dry_run"`
+	Total    int              `json:"total"`
+	Valid    int              `json:"valid"`
+	Imported int64            `json:"imported"`
+	Errors   []ImportRowError `json:"errors"`
+}
+
+// ImportFormat picks the import format from an explicit format name or,
+// failing that, the request content type
+func ImportFormat(format, contentType string) (string, error) {
+	switch strings.ToLower(format) {
+	case ImportCSV, ImportJSON, ImportNDJSON:
+		return strings.ToLower(format), nil
+	case "":
+	default:
+		return "", fmt.Errorf("%w: format must be csv, json or ndjson", ErrInvalidImport)
+	}
+
+	switch contentType {
+	case "text/csv":
+		return ImportCSV, nil
+	case "application/json":
+		return ImportJSON, nil
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return ImportNDJSON, nil
+	default:
+		return "", fmt.Errorf("%w: unsupported content type %q", ErrInvalidImport, contentType)
+	}
+}
+
+// ParseImport reads albums in the given format and validates each row. A
+// malformed CSV header or JSON array fails the whole import; a bad row only
+// fails that row.
+func ParseImport(r io.Reader, format string) ([]ImportRow, error) {
+	switch format {
+	case ImportCSV:
+		return parseCSVImport(r)
+	case ImportJSON:
+		return parseJSONImport(r)
+	case ImportNDJSON:
+		return parseNDJSONImport(r)
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidImport, format)
+	}
+}
+
+func parseCSVImport(r io.Reader) ([]ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: missing CSV header", ErrInvalidImport)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(importColumns, name) {
+			return nil, fmt.Errorf("%w: unknown CSV column %q", ErrInvalidImport, name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("%w: duplicate CSV column %q", ErrInvalidImport, name)
+		}
+		columns[name] = i
+	}
+	for _, name := range importColumns {
+		if _, ok := columns[name]; !ok && name != "currency" {
+			return nil, fmt.Errorf("%w: missing CSV column %q", ErrInvalidImport, name)
+		}
+	}
+
+	rows := make([]ImportRow, 0)
+	for n := 1; ; n++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+
+		if len(record) != len(header) {
+			rows = append(rows, ImportRow{
+				Row:    n,
+				Errors: []string{fmt.Sprintf("expected %d fields, got %d", len(header), len(record))},
+			})
+			continue
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		var price *Money
+		var priceErr error
+		if text := field("price"); text != "" {
+			var parsed Money
+			if parsed, priceErr = ParseMoney(text); priceErr == nil {
+				price = &parsed
+			}
+		}
+
+		rows = append(rows, newImportRow(n, field("title"), field("artist"), field("currency"), price, priceErr))
+	}
+}
+
+func parseJSONImport(r io.Reader) ([]ImportRow, error) {
+	var records []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, fmt.Errorf("%w: body must be a JSON array of albums: %v", ErrInvalidImport, err)
+	}
+
+	rows := make([]ImportRow, 0, len(records))
+	for i, record := range records {
+		rows = append(rows, parseJSONRecord(i+1, record))
+	}
+	return rows, nil
+}
+
+func parseNDJSONImport(r io.Reader) ([]ImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	rows := make([]ImportRow, 0)
+	n := 0
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		n++
+		rows = append(rows, parseJSONRecord(n, line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	return rows, nil
+}
+
+// parseJSONRecord reads one album object. The price is decoded on its own so
+// that a bad price is reported alongside any other problem with the row.
+func parseJSONRecord(n int, data []byte) ImportRow {
+	var record struct {
+		Title    string          `json:"title"`
+		Artist   string          `json:"artist"`
+		Price    json.RawMessage `json:"price"`
+		Currency string          `json:"currency"`
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return ImportRow{Row: n, Errors: []string{"invalid JSON object: " + err.Error()}}
+	}
+
+	var price *Money
+	var priceErr error
+	if len(record.Price) > 0 && string(record.Price) != "null" {
+		var parsed Money
+		if priceErr = parsed.UnmarshalJSON(record.Price); priceErr == nil {
+			price = &parsed
+		}
+	}
+
+	return newImportRow(n, strings.TrimSpace(record.Title), strings.TrimSpace(record.Artist), record.Currency, price, priceErr)
+}
+
+// newImportRow validates a record, collecting every problem with it
+func newImportRow(n int, title, artist, currency string, price *Money, priceErr error) ImportRow {
+	row := ImportRow{Row: n, Album: Album{Title: title, Artist: artist, Currency: currency}}
+
+	if title == "" {
+		row.Errors = append(row.Errors, "title is required")
+	}
+	if artist == "" {
+		row.Errors = append(row.Errors, "artist is required")
+	}
+
+	switch {
+	case priceErr != nil:
+		row.Errors = append(row.Errors, "price: "+priceErr.Error())
+	case price == nil:
+		row.Errors = append(row.Errors, "price is required")
+	default:
+		row.Album.Price = *price
+	}
+
+	if err := row.Album.NormalizePricing(); err != nil {
+		row.Errors = append(row.Errors, err.Error())
+	}
+
+	return row
+}
@@ -0,0 +1,111 @@
+package album
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportFormat(t *testing.T) {
+	tests := []struct {
+		format      string
+		contentType string
+		want        string
+	}{
+		{"", "text/csv", ImportCSV},
+		{"", "application/json", ImportJSON},
+		{"", "application/x-ndjson", ImportNDJSON},
+		{"NDJSON", "application/json", ImportNDJSON},
+		{"csv", "", ImportCSV},
+	}
+
+	for _, tt := range tests {
+		got, err := ImportFormat(tt.format, tt.contentType)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got)
+	}
+
+	_, err := ImportFormat("xml", "text/csv")
+	assert.ErrorIs(t, err, ErrInvalidImport)
+	_, err = ImportFormat("", "text/plain")
+	assert.ErrorIs(t, err, ErrInvalidImport)
+}
+
+func TestParseImport(t *testing.T) {
+	want := []Album{
+		{Title: "Blue Train", Artist: "John Coltrane", Price: 5699, Currency: "USD"},
+		{Title: "Giant Steps", Artist: "John Coltrane", Price: 1799, Currency: "EUR"},
+	}
+
+	tests := []struct {
+		format string
+		body   string
+	}{
+		{ImportCSV, "Price,Title,Artist,Currency\n56.99,Blue Train,John Coltrane,\n17.99,Giant Steps,John Coltrane,eur\n"},
+		{ImportJSON, `[{"title": "Blue Train", "artist": "John Coltrane", "price": 56.99},
+			{"title": "Giant Steps", "artist": "John Coltrane", "price": "17.99", "currency": "eur"}]`},
+		{ImportNDJSON, "{\"title\": \"Blue Train\", \"artist\": \"John Coltrane\", \"price\": 56.99}\n\n" +
+			"{\"title\": \"Giant Steps\", \"artist\": \"John Coltrane\", \"price\": 17.99, \"currency\": \"EUR\"}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			rows, err := ParseImport(strings.NewReader(tt.body), tt.format)
+			require.NoError(t, err)
+			require.Len(t, rows, 2)
+			for i, row := range rows {
+				assert.Equal(t, i+1, row.Row)
+				assert.Empty(t, row.Errors)
+				assert.Equal(t, want[i], row.Album)
+			}
+		})
+	}
+}
+
+func TestParseImport_RowErrors(t *testing.T) {
+	body := "title,artist,price\n" +
+		"Blue Train,John Coltrane,56.999\n" +
+		",,\n" +
+		"Giant Steps,John Coltrane\n" +
+		"The Wall,Pink Floyd,24.99\n"
+
+	rows, err := ParseImport(strings.NewReader(body), ImportCSV)
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+
+	assert.Len(t, rows[0].Errors, 1)
+	assert.Contains(t, rows[0].Errors[0], "more than two decimal places")
+	assert.Equal(t, []string{"title is required", "artist is required", "price is required"}, rows[1].Errors)
+	assert.Equal(t, []string{"expected 3 fields, got 2"}, rows[2].Errors)
+	assert.Empty(t, rows[3].Errors)
+
+	rows, err = ParseImport(strings.NewReader("{\"title\": 1}\n{\"title\": \"x\", \"artist\": \"y\", \"price\": 1, \"currency\": \"dollars\"}\n"), ImportNDJSON)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Contains(t, rows[0].Errors[0], "invalid JSON object")
+	assert.Len(t, rows[1].Errors, 1)
+}
+
+func TestParseImport_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		body   string
+	}{
+		{"empty CSV", ImportCSV, ""},
+		{"missing column", ImportCSV, "title,artist\nBlue Train,John Coltrane\n"},
+		{"unknown column", ImportCSV, "title,artist,price,label\n"},
+		{"duplicate column", ImportCSV, "title,artist,price,title\n"},
+		{"not an array", ImportJSON, `{"title": "Blue Train"}`},
+		{"truncated array", ImportJSON, `[{"title": "Blue Train"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseImport(strings.NewReader(tt.body), tt.format)
+			assert.ErrorIs(t, err, ErrInvalidImport)
+		})
+	}
+}
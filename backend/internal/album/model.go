@@ -6,11 +6,44 @@ import (
 
 // Album represents an album record in the database
 type Album struct {
-	ID        int        `json:"id"`
-	Title     string     `json:"title" binding:"required"`
-	Artist    string     `json:"artist" binding:"required"`
-	Price     float64    `json:"price" binding:"required"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	ID        int          `json:"id"`
+	Title     string       `json:"title"`
+	Artist    string       `json:"artist"`
+	Price     Money        `json:"price"`
+	Currency  string       `json:"currency"`
+	Prices    []PricePoint `json:"prices,omitempty"`
+	Version   int          `json:"version"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+	DeletedAt *time.Time   `json:"deleted_at,omitempty"`
+	// Converted is set when a caller asks for prices in another currency
+	Converted *ConvertedPrice `json:"converted_price,omitempty"`
+}
+
+// albumRequest is the body of POST and PUT /albums. Price is a pointer so an
+// explicit price of 0 is told apart from a missing one.
+type albumRequest struct {
+	Title    string       `json:"title" binding:"required"`
+	Artist   string       `json:"artist" binding:"required"`
+	Price    *Money       `json:"price" binding:"required"`
+	Currency string       `json:"currency"`
+	Prices   []PricePoint `json:"prices,omitempty"`
+}
+
+// ListResponse is the paginated response for GET /albums
+type ListResponse struct {
+	Albums     []Album `json:"albums"`
+	Total      int     `json:"total"`
+	Limit      int     `json:"limit"`
+	Offset     int     `json:"offset"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+	PrevCursor string  `json:"prev_cursor,omitempty"`
+	Links      Links   `json:"links"`
+}
+
+// Links holds navigation links for a paginated response
+type Links struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
 }
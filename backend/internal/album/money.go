@@ -0,0 +1,145 @@
+package album
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// MaxMoney is the largest amount a DECIMAL(10, 2) column can hold
+const MaxMoney Money = 9999999999
+
+// ErrInvalidMoney is returned when an amount is negative, too large or more
+// precise than a cent
+var ErrInvalidMoney = errors.New("invalid amount")
+
+// Money is an exact amount in minor units (cents). It is written to JSON as a
+// decimal number with two fractional digits and accepts either a number or a
+// string when read.
+type Money int64
+
+// ParseMoney parses a decimal amount such as "19.99"
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "-") {
+		return 0, fmt.Errorf("%w: %q is negative", ErrInvalidMoney, s)
+	}
+
+	whole, frac, hasPoint := strings.Cut(s, ".")
+	if !isDigits(whole) || (hasPoint && !isDigits(frac)) {
+		return 0, fmt.Errorf("%w: %q is not a decimal number", ErrInvalidMoney, s)
+	}
+	if len(frac) > 2 {
+		return 0, fmt.Errorf("%w: %q has more than two decimal places", ErrInvalidMoney, s)
+	}
+
+	whole = strings.TrimLeft(whole, "0")
+	if len(whole) > 8 {
+		return 0, fmt.Errorf("%w: %q exceeds %s", ErrInvalidMoney, s, MaxMoney)
+	}
+
+	cents, err := strconv.ParseInt(whole+frac+strings.Repeat("0", 2-len(frac)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a decimal number", ErrInvalidMoney, s)
+	}
+
+	return Money(cents), nil
+}
+
+// Validate reports whether the amount can be stored as a price
+func (m Money) Validate() error {
+	if m < 0 {
+		return fmt.Errorf("%w: %s is negative", ErrInvalidMoney, m)
+	}
+	if m > MaxMoney {
+		return fmt.Errorf("%w: %s exceeds %s", ErrInvalidMoney, m, MaxMoney)
+	}
+	return nil
+}
+
+// String formats the amount with two fractional digits
+func (m Money) String() string {
+	sign := ""
+	if m < 0 {
+		sign = "-"
+		m = -m
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, m/100, m%100)
+}
+
+// MarshalJSON writes the amount as a JSON number
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON reads a JSON number or string without going through float64
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	text := string(data)
+	if strings.HasPrefix(text, `"`) {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+	}
+
+	parsed, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+// ScanNumeric implements pgtype.NumericScanner
+func (m *Money) ScanNumeric(n pgtype.Numeric) error {
+	if !n.Valid || n.NaN || n.InfinityModifier != pgtype.Finite {
+		return fmt.Errorf("%w: cannot scan %v into Money", ErrInvalidMoney, n)
+	}
+
+	cents := new(big.Int).Set(n.Int)
+	ten := big.NewInt(10)
+	for exp := n.Exp + 2; exp > 0; exp-- {
+		cents.Mul(cents, ten)
+	}
+	for exp := n.Exp + 2; exp < 0; exp++ {
+		var remainder big.Int
+		cents.QuoRem(cents, ten, &remainder)
+		if remainder.Sign() != 0 {
+			return fmt.Errorf("%w: database value has more than two decimal places", ErrInvalidMoney)
+		}
+	}
+	if !cents.IsInt64() {
+		return fmt.Errorf("%w: database value is out of range", ErrInvalidMoney)
+	}
+
+	*m = Money(cents.Int64())
+	return nil
+}
+
+// NumericValue implements pgtype.NumericValuer
+func (m Money) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{Int: big.NewInt(int64(m)), Exp: -2, Valid: true}, nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
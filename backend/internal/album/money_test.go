@@ -0,0 +1,64 @@
+package album
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	valid := map[string]Money{
+		"19.99":       1999,
+		"0":           0,
+		"0.5":         50,
+		"007.10":      710,
+		"99999999.99": MaxMoney,
+	}
+	for input, want := range valid {
+		got, err := ParseMoney(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	for _, input := range []string{"", "-1", "19.999", "1e3", "1.", ".5", "1,50", "100000000", "abc"} {
+		_, err := ParseMoney(input)
+		assert.ErrorIs(t, err, ErrInvalidMoney, input)
+	}
+}
+
+func TestMoney_JSON(t *testing.T) {
+	var a Album
+	require.NoError(t, json.Unmarshal([]byte(`{"price": 19.99}`), &a))
+	assert.Equal(t, Money(1999), a.Price)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"price": "0.10"}`), &a))
+	assert.Equal(t, Money(10), a.Price)
+
+	data, err := json.Marshal(Money(1999))
+	require.NoError(t, err)
+	assert.Equal(t, "19.99", string(data))
+
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"price": -5}`), &a), ErrInvalidMoney)
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"price": 1.005}`), &a), ErrInvalidMoney)
+}
+
+func TestMoney_Numeric(t *testing.T) {
+	var m Money
+	require.NoError(t, m.ScanNumeric(pgtype.Numeric{Int: big.NewInt(1999), Exp: -2, Valid: true}))
+	assert.Equal(t, Money(1999), m)
+
+	require.NoError(t, m.ScanNumeric(pgtype.Numeric{Int: big.NewInt(25), Exp: 0, Valid: true}))
+	assert.Equal(t, Money(2500), m)
+
+	assert.Error(t, m.ScanNumeric(pgtype.Numeric{Int: big.NewInt(19999), Exp: -3, Valid: true}))
+	assert.Error(t, m.ScanNumeric(pgtype.Numeric{}))
+
+	n, err := Money(1999).NumericValue()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1999), n.Int.Int64())
+	assert.Equal(t, int32(-2), n.Exp)
+}
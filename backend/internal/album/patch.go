@@ -0,0 +1,452 @@
+package album
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MergePatchContentType is the media type of RFC 7396 JSON Merge Patch documents
+	MergePatchContentType = "application/merge-patch+json"
+	// JSONPatchContentType is the media type of RFC 6902 JSON Patch documents
+	JSONPatchContentType = "application/json-patch+json"
+)
+
+var (
+	// ErrInvalidPatch is returned when a patch document is malformed or would
+	// leave the album invalid
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrPatchTestFailed is returned when a JSON Patch "test" operation does not hold
+	ErrPatchTestFailed = errors.New("patch test failed")
+)
+
+// Patch is a partial update of an album. Nil fields are left unchanged.
+// Prices, when set, replaces every price point.
+type Patch struct {
+	Title    *string
+	Artist   *string
+	Price    *Money
+	Currency *string
+	Prices   *[]PricePoint
+}
+
+// patchableFields are the album fields a patch may change
+var patchableFields = map[string]bool{"title": true, "artist": true, "price": true, "currency": true, "prices": true}
+
+// readOnlyFields may appear in JSON Patch "test" operations but cannot be changed
+var readOnlyFields = map[string]bool{"id": true, "version": true}
+
+// PatchFromFields builds a patch from JSON object members. Absent members are
+// left unchanged, explicit nulls are rejected because every album field is
+// required, and zero values such as a price of 0 are applied as given.
+func PatchFromFields(fields map[string]json.RawMessage) (Patch, error) {
+	var patch Patch
+
+	for _, name := range sortedKeys(fields) {
+		raw := fields[name]
+		if !patchableFields[name] {
+			return Patch{}, fmt.Errorf("%w: field %q cannot be changed", ErrInvalidPatch, name)
+		}
+		if isNull(raw) {
+			return Patch{}, fmt.Errorf("%w: %s cannot be null", ErrInvalidPatch, name)
+		}
+
+		var err error
+		switch name {
+		case "title":
+			patch.Title = new(string)
+			err = json.Unmarshal(raw, patch.Title)
+		case "artist":
+			patch.Artist = new(string)
+			err = json.Unmarshal(raw, patch.Artist)
+		case "price":
+			patch.Price = new(Money)
+			err = json.Unmarshal(raw, patch.Price)
+		case "currency":
+			patch.Currency = new(string)
+			err = json.Unmarshal(raw, patch.Currency)
+		case "prices":
+			patch.Prices = &[]PricePoint{}
+			err = json.Unmarshal(raw, patch.Prices)
+		}
+		if errors.Is(err, ErrInvalidMoney) {
+			return Patch{}, fmt.Errorf("%w: %s: %v", ErrInvalidPatch, name, err)
+		}
+		if err != nil {
+			return Patch{}, fmt.Errorf("%w: %s has the wrong type", ErrInvalidPatch, name)
+		}
+	}
+
+	return patch, nil
+}
+
+// ParseMergePatch parses an RFC 7396 JSON Merge Patch document
+func ParseMergePatch(data []byte) (Patch, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil || fields == nil {
+		return Patch{}, fmt.Errorf("%w: merge patch must be a JSON object", ErrInvalidPatch)
+	}
+
+	return PatchFromFields(fields)
+}
+
+// patchOperation is a single RFC 6902 operation
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// ParseJSONPatch applies an RFC 6902 JSON Patch document to the current album
+// and returns the resulting changes. Operations apply in order and the whole
+// document fails if any operation does.
+func ParseJSONPatch(data []byte, current *Album) (Patch, error) {
+	var ops []patchOperation
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return Patch{}, fmt.Errorf("%w: JSON patch must be an array of operations", ErrInvalidPatch)
+	}
+
+	doc, err := patchDocument(current)
+	if err != nil {
+		return Patch{}, err
+	}
+
+	for i, op := range ops {
+		if err := op.apply(doc); err != nil {
+			return Patch{}, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	changed := make(map[string]json.RawMessage)
+	for name := range patchableFields {
+		value, ok := doc[name]
+		if !ok {
+			return Patch{}, fmt.Errorf("%w: %s is required", ErrInvalidPatch, name)
+		}
+		changed[name] = value
+	}
+
+	return PatchFromFields(changed)
+}
+
+// apply runs one operation against the document
+func (op patchOperation) apply(doc map[string]json.RawMessage) error {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return err
+	}
+
+	if op.Value == nil && (op.Op == "test" || op.Op == "add" || op.Op == "replace") {
+		return fmt.Errorf("%w: missing value", ErrInvalidPatch)
+	}
+
+	switch op.Op {
+	case "test":
+		actual, err := path.get(doc)
+		if err != nil || !jsonEqual(actual, op.Value) {
+			return ErrPatchTestFailed
+		}
+		return nil
+	case "add", "replace", "remove":
+		return path.update(doc, op.Op, op.Value)
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return err
+		}
+		value, err := from.get(doc)
+		if err != nil {
+			return fmt.Errorf("%w: from path does not exist", ErrInvalidPatch)
+		}
+		if op.Op == "move" && op.From != op.Path {
+			if strings.HasPrefix(op.Path, op.From+"/") {
+				return fmt.Errorf("%w: cannot move a value into itself", ErrInvalidPatch)
+			}
+			if err := from.update(doc, "remove", nil); err != nil {
+				return err
+			}
+		}
+		return path.update(doc, "add", value)
+	default:
+		return fmt.Errorf("%w: unknown operation %q", ErrInvalidPatch, op.Op)
+	}
+}
+
+// patchDocument is the JSON view of an album that JSON Patch paths refer to
+func patchDocument(a *Album) (map[string]json.RawMessage, error) {
+	prices := a.Prices
+	if prices == nil {
+		prices = []PricePoint{}
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"id":       a.ID,
+		"version":  a.Version,
+		"title":    a.Title,
+		"artist":   a.Artist,
+		"price":    a.Price,
+		"currency": a.Currency,
+		"prices":   prices,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]json.RawMessage
+	err = json.Unmarshal(data, &doc)
+	return doc, err
+}
+
+// pointer is a JSON Pointer into the patch document: a top-level album field
+// and, for fields holding arrays or objects such as prices, the reference
+// tokens below it
+type pointer struct {
+	field string
+	rest  []string
+}
+
+// parsePointer parses an RFC 6901 JSON Pointer such as "/price" or
+// "/prices/0/amount"
+func parsePointer(raw string) (pointer, error) {
+	if !strings.HasPrefix(raw, "/") {
+		return pointer{}, fmt.Errorf("%w: unsupported path %q", ErrInvalidPatch, raw)
+	}
+
+	unescape := strings.NewReplacer("~1", "/", "~0", "~")
+	tokens := strings.Split(raw[1:], "/")
+	for i := range tokens {
+		tokens[i] = unescape.Replace(tokens[i])
+	}
+
+	if !patchableFields[tokens[0]] && !readOnlyFields[tokens[0]] {
+		return pointer{}, fmt.Errorf("%w: unknown path %q", ErrInvalidPatch, raw)
+	}
+	return pointer{field: tokens[0], rest: tokens[1:]}, nil
+}
+
+// get returns the value the pointer refers to
+func (p pointer) get(doc map[string]json.RawMessage) (json.RawMessage, error) {
+	value, ok := doc[p.field]
+	if !ok {
+		return nil, fmt.Errorf("%w: path does not exist", ErrInvalidPatch)
+	}
+	if len(p.rest) == 0 {
+		return value, nil
+	}
+
+	node, err := decodeNode(value)
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range p.rest {
+		switch n := node.(type) {
+		case []interface{}:
+			i, err := arrayIndex(token, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = n[i]
+		case map[string]interface{}:
+			child, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: path does not exist", ErrInvalidPatch)
+			}
+			node = child
+		default:
+			return nil, fmt.Errorf("%w: path does not exist", ErrInvalidPatch)
+		}
+	}
+	return json.Marshal(node)
+}
+
+// update adds, replaces or removes the value the pointer refers to
+func (p pointer) update(doc map[string]json.RawMessage, op string, value json.RawMessage) error {
+	if err := writable(p.field); err != nil {
+		return err
+	}
+
+	current, exists := doc[p.field]
+	if len(p.rest) == 0 {
+		if op != "add" && !exists {
+			return fmt.Errorf("%w: path does not exist", ErrInvalidPatch)
+		}
+		if op == "remove" {
+			delete(doc, p.field)
+		} else {
+			doc[p.field] = value
+		}
+		return nil
+	}
+
+	if !exists {
+		return fmt.Errorf("%w: path does not exist", ErrInvalidPatch)
+	}
+	node, err := decodeNode(current)
+	if err != nil {
+		return err
+	}
+	var child interface{}
+	if value != nil {
+		if child, err = decodeNode(value); err != nil {
+			return err
+		}
+	}
+
+	if node, err = updateNode(node, p.rest, op, child); err != nil {
+		return err
+	}
+	doc[p.field], err = json.Marshal(node)
+	return err
+}
+
+// updateNode applies op at tokens below node and returns the updated node.
+// Within arrays "add" inserts before the index, or appends for "-".
+func updateNode(node interface{}, tokens []string, op string, value interface{}) (interface{}, error) {
+	token, last := tokens[0], len(tokens) == 1
+
+	switch n := node.(type) {
+	case []interface{}:
+		if last && op == "add" {
+			i := len(n)
+			if token != "-" {
+				var err error
+				if i, err = arrayIndex(token, len(n)); err != nil {
+					return nil, err
+				}
+			}
+			return append(n[:i], append([]interface{}{value}, n[i:]...)...), nil
+		}
+
+		i, err := arrayIndex(token, len(n)-1)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case !last:
+			n[i], err = updateNode(n[i], tokens[1:], op, value)
+		case op == "remove":
+			return append(n[:i], n[i+1:]...), nil
+		default:
+			n[i] = value
+		}
+		return n, err
+	case map[string]interface{}:
+		child, ok := n[token]
+		switch {
+		case !last:
+			if !ok {
+				return nil, fmt.Errorf("%w: path does not exist", ErrInvalidPatch)
+			}
+			var err error
+			n[token], err = updateNode(child, tokens[1:], op, value)
+			return n, err
+		case op != "add" && !ok:
+			return nil, fmt.Errorf("%w: path does not exist", ErrInvalidPatch)
+		case op == "remove":
+			delete(n, token)
+		default:
+			n[token] = value
+		}
+		return n, nil
+	default:
+		return nil, fmt.Errorf("%w: path does not exist", ErrInvalidPatch)
+	}
+}
+
+// arrayIndex parses an array index token no greater than max
+func arrayIndex(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || strconv.Itoa(i) != token {
+		return 0, fmt.Errorf("%w: %q is not an array index", ErrInvalidPatch, token)
+	}
+	if i > max {
+		return 0, fmt.Errorf("%w: index %d is out of range", ErrInvalidPatch, i)
+	}
+	return i, nil
+}
+
+// decodeNode decodes a JSON value, keeping numbers exact so prices survive
+// being patched
+func decodeNode(raw json.RawMessage) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var node interface{}
+	if err := decoder.Decode(&node); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return node, nil
+}
+
+func writable(name string) error {
+	if readOnlyFields[name] {
+		return fmt.Errorf("%w: %s is read-only", ErrInvalidPatch, name)
+	}
+	return nil
+}
+
+// Apply applies the patch to an album and validates the result
+func (p Patch) Apply(a *Album) error {
+	updated := *a
+	if p.Title != nil {
+		updated.Title = *p.Title
+	}
+	if p.Artist != nil {
+		updated.Artist = *p.Artist
+	}
+	if p.Price != nil {
+		updated.Price = *p.Price
+	}
+	if p.Currency != nil {
+		updated.Currency = *p.Currency
+	}
+	if p.Prices != nil {
+		updated.Prices = append([]PricePoint{}, *p.Prices...)
+	}
+
+	if strings.TrimSpace(updated.Title) == "" {
+		return fmt.Errorf("%w: title cannot be empty", ErrInvalidPatch)
+	}
+	if strings.TrimSpace(updated.Artist) == "" {
+		return fmt.Errorf("%w: artist cannot be empty", ErrInvalidPatch)
+	}
+	if err := updated.Price.Validate(); err != nil {
+		return fmt.Errorf("%w: price: %v", ErrInvalidPatch, err)
+	}
+	if err := updated.NormalizePricing(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	*a = updated
+	return nil
+}
+
+func isNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+// jsonEqual compares two JSON values structurally
+func jsonEqual(a, b json.RawMessage) bool {
+	var x, y interface{}
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return false
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+// sortedKeys makes error messages deterministic
+func sortedKeys(fields map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,120 @@
+package album
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMergePatch(t *testing.T) {
+	a := &Album{ID: 1, Title: "The Wall", Artist: "Pink Floyd", Price: 2499}
+
+	// Absent fields are unchanged and a zero price is a real value
+	patch, err := ParseMergePatch([]byte(`{"price": 0}`))
+	require.NoError(t, err)
+	require.NoError(t, patch.Apply(a))
+	assert.Equal(t, "The Wall", a.Title)
+	assert.Equal(t, Money(0), a.Price)
+
+	_, err = ParseMergePatch([]byte(`{"title": null}`))
+	assert.ErrorIs(t, err, ErrInvalidPatch, "null removes a field, and every field is required")
+
+	_, err = ParseMergePatch([]byte(`{"id": 2}`))
+	assert.ErrorIs(t, err, ErrInvalidPatch)
+
+	_, err = ParseMergePatch([]byte(`{"price": "free"}`))
+	assert.ErrorIs(t, err, ErrInvalidPatch)
+
+	_, err = ParseMergePatch([]byte(`[]`))
+	assert.ErrorIs(t, err, ErrInvalidPatch)
+}
+
+func TestPatch_ApplyValidates(t *testing.T) {
+	a := &Album{Title: "The Wall", Artist: "Pink Floyd", Price: 2499}
+
+	empty := ""
+	assert.ErrorIs(t, Patch{Title: &empty}.Apply(a), ErrInvalidPatch)
+
+	negative := Money(-100)
+	assert.ErrorIs(t, Patch{Price: &negative}.Apply(a), ErrInvalidPatch)
+
+	// A failed patch leaves the album untouched
+	assert.Equal(t, "The Wall", a.Title)
+	assert.Equal(t, Money(2499), a.Price)
+}
+
+func TestParseJSONPatch(t *testing.T) {
+	current := &Album{ID: 1, Version: 3, Title: "The Wall", Artist: "Pink Floyd", Price: 2499}
+
+	patch, err := ParseJSONPatch([]byte(`[
+		{"op": "test", "path": "/version", "value": 3},
+		{"op": "replace", "path": "/price", "value": 0},
+		{"op": "copy", "from": "/artist", "path": "/title"}
+	]`), current)
+	require.NoError(t, err)
+
+	a := *current
+	require.NoError(t, patch.Apply(&a))
+	assert.Equal(t, "Pink Floyd", a.Title)
+	assert.Equal(t, Money(0), a.Price)
+
+	// Removing a required field only works if it is added back
+	_, err = ParseJSONPatch([]byte(`[{"op": "remove", "path": "/title"}]`), current)
+	assert.ErrorIs(t, err, ErrInvalidPatch)
+	_, err = ParseJSONPatch([]byte(`[
+		{"op": "remove", "path": "/title"},
+		{"op": "add", "path": "/title", "value": "Animals"}
+	]`), current)
+	assert.NoError(t, err)
+}
+
+func TestParseJSONPatch_Prices(t *testing.T) {
+	current := &Album{ID: 1, Version: 3, Title: "The Wall", Artist: "Pink Floyd", Price: 2499, Currency: "USD",
+		Prices: []PricePoint{{Currency: "EUR", Amount: 2299}, {Currency: "GBP", Amount: 1999}}}
+
+	patch, err := ParseJSONPatch([]byte(`[
+		{"op": "test", "path": "/prices/0/currency", "value": "EUR"},
+		{"op": "replace", "path": "/prices/0/amount", "value": 21.50},
+		{"op": "remove", "path": "/prices/1"},
+		{"op": "add", "path": "/prices/-", "value": {"currency": "JPY", "amount": 3500}},
+		{"op": "add", "path": "/prices/0", "value": {"currency": "CAD", "amount": 32.99}}
+	]`), current)
+	require.NoError(t, err)
+
+	a := *current
+	require.NoError(t, patch.Apply(&a))
+	assert.Equal(t, []PricePoint{
+		{Currency: "CAD", Amount: 3299},
+		{Currency: "EUR", Amount: 2150},
+		{Currency: "JPY", Amount: 350000},
+	}, a.Prices)
+}
+
+func TestParseJSONPatch_Errors(t *testing.T) {
+	current := &Album{ID: 1, Version: 3, Title: "The Wall", Artist: "Pink Floyd", Price: 2499}
+
+	tests := map[string]struct {
+		doc  string
+		want error
+	}{
+		"failed test":     {`[{"op": "test", "path": "/title", "value": "Animals"}]`, ErrPatchTestFailed},
+		"read-only field": {`[{"op": "replace", "path": "/version", "value": 4}]`, ErrInvalidPatch},
+		"unknown path":    {`[{"op": "add", "path": "/genre", "value": "Rock"}]`, ErrInvalidPatch},
+		"nested path":     {`[{"op": "add", "path": "/title/0", "value": "A"}]`, ErrInvalidPatch},
+		"index range":     {`[{"op": "replace", "path": "/prices/0", "value": {}}]`, ErrInvalidPatch},
+		"bad index":       {`[{"op": "add", "path": "/prices/01", "value": {}}]`, ErrInvalidPatch},
+		"missing member":  {`[{"op": "test", "path": "/prices/0/amount", "value": 1}]`, ErrPatchTestFailed},
+		"unknown op":      {`[{"op": "merge", "path": "/title", "value": "A"}]`, ErrInvalidPatch},
+		"missing value":   {`[{"op": "replace", "path": "/title"}]`, ErrInvalidPatch},
+		"null value":      {`[{"op": "replace", "path": "/price", "value": null}]`, ErrInvalidPatch},
+		"not an array":    {`{"op": "replace"}`, ErrInvalidPatch},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseJSONPatch([]byte(tt.doc), current)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
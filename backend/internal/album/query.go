@@ -0,0 +1,303 @@
+package album
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultLimit is the page size used when none is requested
+	DefaultLimit = 50
+	// MaxLimit is the largest page size a caller may request
+	MaxLimit = 500
+)
+
+var (
+	// ErrInvalidQuery is returned when list options cannot be satisfied
+	ErrInvalidQuery = errors.New("invalid query")
+)
+
+// sortColumn maps a public sort field to its column and SQL type
+type sortColumn struct {
+	column string
+	cast   string
+}
+
+// sortColumns lists every column albums can be ordered by.
+// Only these names ever reach the ORDER BY clause.
+var sortColumns = map[string]sortColumn{
+	"id":         {column: "id", cast: "int"},
+	"title":      {column: "title", cast: "text"},
+	"artist":     {column: "artist", cast: "text"},
+	"price":      {column: "price", cast: "numeric"},
+	"created_at": {column: "created_at", cast: "timestamp"},
+	"updated_at": {column: "updated_at", cast: "timestamp"},
+}
+
+// cursorTimeLayout matches the precision of a Postgres TIMESTAMP column
+const cursorTimeLayout = "2006-01-02T15:04:05.999999"
+
+// ListOptions controls filtering, sorting and pagination of album lists
+type ListOptions struct {
+	Limit         int
+	Offset        int
+	Cursor        string
+	Search        string
+	Artist        string
+	TitleContains string
+	MinPrice      *Money
+	MaxPrice      *Money
+	Sort          string
+	Desc          bool
+	// PriceCurrency is the currency of MinPrice and MaxPrice, DefaultCurrency
+	// when empty. Albums are compared by their price in it, found the same
+	// way as a converted_price.
+	PriceCurrency string
+	// RatesAt picks the exchange rates price filters convert with; zero means now
+	RatesAt time.Time
+	// Deleted lists soft-deleted albums instead of live ones
+	Deleted bool
+	// IncludeDeleted lists live and soft-deleted albums together
+	IncludeDeleted bool
+}
+
+// ListResult is a single page of albums
+type ListResult struct {
+	Albums     []Album
+	Total      int
+	Limit      int
+	NextCursor string
+	PrevCursor string
+}
+
+// cursor is the decoded form of an opaque pagination cursor.
+// It records the sort order and the position of the boundary row.
+type cursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v"`
+	ID    int    `json:"i"`
+	Prev  bool   `json:"p,omitempty"`
+}
+
+// normalize validates options and fills in defaults
+func (o *ListOptions) normalize() error {
+	if o.Limit <= 0 {
+		o.Limit = DefaultLimit
+	}
+	if o.Limit > MaxLimit {
+		o.Limit = MaxLimit
+	}
+	if o.Offset < 0 {
+		return fmt.Errorf("%w: offset must not be negative", ErrInvalidQuery)
+	}
+	if o.Sort == "" {
+		o.Sort = "id"
+	}
+	if _, ok := sortColumns[o.Sort]; !ok {
+		return fmt.Errorf("%w: unknown sort field %q", ErrInvalidQuery, o.Sort)
+	}
+	if o.MinPrice != nil && o.MaxPrice != nil && *o.MinPrice > *o.MaxPrice {
+		return fmt.Errorf("%w: min_price must not exceed max_price", ErrInvalidQuery)
+	}
+	if o.MinPrice != nil || o.MaxPrice != nil {
+		if o.PriceCurrency == "" {
+			o.PriceCurrency = DefaultCurrency
+		}
+		currency, err := ParseCurrency(o.PriceCurrency)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+		}
+		o.PriceCurrency = currency
+		if o.RatesAt.IsZero() {
+			o.RatesAt = time.Now()
+		}
+	}
+	if o.Cursor != "" && o.Offset > 0 {
+		return fmt.Errorf("%w: offset cannot be combined with cursor", ErrInvalidQuery)
+	}
+	return nil
+}
+
+// encodeCursor builds an opaque cursor positioned at the given album
+func encodeCursor(a Album, sort string, desc, prev bool) string {
+	data, _ := json.Marshal(cursor{
+		Sort:  sort,
+		Desc:  desc,
+		Value: sortValue(a, sort),
+		ID:    a.ID,
+		Prev:  prev,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses an opaque cursor and checks it matches the sort order
+func decodeCursor(s string, sort string, desc bool) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+
+	if c.Sort != sort || c.Desc != desc {
+		return nil, fmt.Errorf("%w: cursor does not match sort order", ErrInvalidQuery)
+	}
+
+	return &c, nil
+}
+
+// sortValue renders an album's sort column as text Postgres can cast back
+func sortValue(a Album, sort string) string {
+	switch sort {
+	case "title":
+		return a.Title
+	case "artist":
+		return a.Artist
+	case "price":
+		return a.Price.String()
+	case "created_at":
+		return a.CreatedAt.Format(cursorTimeLayout)
+	case "updated_at":
+		return a.UpdatedAt.Format(cursorTimeLayout)
+	default:
+		return strconv.Itoa(a.ID)
+	}
+}
+
+// priceInCurrency is an album's price in the currency $%[1]d, chosen as
+// Converter.Convert does: the album's own price, a price point, or the price
+// converted at the exchange rate in effect at $%[2]d. A rate recorded in the
+// opposite direction is inverted and rounded to its stored precision first.
+// Albums with no price or rate in the currency yield NULL and never match.
+const priceInCurrency = `COALESCE(
+	CASE WHEN albums.currency = $%[1]d THEN albums.price END,
+	(SELECT p.amount FROM album_prices p WHERE p.album_id = albums.id AND p.currency = $%[1]d),
+	(SELECT ROUND(albums.price * CASE WHEN r.base_currency = albums.currency THEN r.rate ELSE ROUND(1 / r.rate, 8) END, 2)
+		FROM exchange_rates r
+		WHERE ((r.base_currency = albums.currency AND r.quote_currency = $%[1]d)
+			OR (r.base_currency = $%[1]d AND r.quote_currency = albums.currency))
+			AND r.effective_at <= $%[2]d
+		ORDER BY r.base_currency = albums.currency DESC, r.effective_at DESC
+		LIMIT 1))`
+
+// filterClause builds the WHERE clause shared by the page and count
+// queries. It always confines the rows to the tenant, which is $1.
+func filterClause(tenant string, opts ListOptions) (string, []interface{}) {
+	conditions := []string{"tenant_id = $1"}
+	args := []interface{}{tenant}
+	switch {
+	case opts.IncludeDeleted:
+	case opts.Deleted:
+		conditions = append(conditions, "deleted_at IS NOT NULL")
+	default:
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+
+	if opts.Search != "" {
+		args = append(args, "%"+escapeLike(opts.Search)+"%")
+		conditions = append(conditions, fmt.Sprintf("(title ILIKE $%d OR artist ILIKE $%d)", len(args), len(args)))
+	}
+	if opts.Artist != "" {
+		args = append(args, opts.Artist)
+		conditions = append(conditions, fmt.Sprintf("LOWER(artist) = LOWER($%d)", len(args)))
+	}
+	if opts.TitleContains != "" {
+		args = append(args, "%"+escapeLike(opts.TitleContains)+"%")
+		conditions = append(conditions, fmt.Sprintf("title ILIKE $%d", len(args)))
+	}
+	if opts.MinPrice != nil || opts.MaxPrice != nil {
+		args = append(args, opts.PriceCurrency, opts.RatesAt)
+		price := fmt.Sprintf(priceInCurrency, len(args)-1, len(args))
+		if opts.MinPrice != nil {
+			args = append(args, *opts.MinPrice)
+			conditions = append(conditions, fmt.Sprintf("%s >= $%d", price, len(args)))
+		}
+		if opts.MaxPrice != nil {
+			args = append(args, *opts.MaxPrice)
+			conditions = append(conditions, fmt.Sprintf("%s <= $%d", price, len(args)))
+		}
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// buildListQuery builds the page query for the given options and cursor.
+// It fetches one row beyond the limit so the caller can tell whether
+// another page exists.
+func buildListQuery(tenant string, opts ListOptions, cur *cursor) (string, []interface{}) {
+	where, args := filterClause(tenant, opts)
+	col := sortColumns[opts.Sort]
+
+	// Walking backwards from a prev cursor flips both the comparison
+	// and the ordering; the caller reverses the rows afterwards.
+	desc := opts.Desc
+	if cur != nil && cur.Prev {
+		desc = !desc
+	}
+
+	direction, comparison := "ASC", ">"
+	if desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if cur != nil {
+		args = append(args, cur.Value, cur.ID)
+		where += fmt.Sprintf(" AND (%s, id) %s ($%d::%s, $%d)",
+			col.column, comparison, len(args)-1, col.cast, len(args))
+	}
+
+	args = append(args, opts.Limit+1)
+	query := fmt.Sprintf(`
+		SELECT id, title, artist, price, currency, version, created_at, updated_at, deleted_at
+		FROM albums
+		WHERE %s
+		ORDER BY %s %s, id %s
+		LIMIT $%d`, where, col.column, direction, direction, len(args))
+
+	if cur == nil && opts.Offset > 0 {
+		args = append(args, opts.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	return query, args
+}
+
+// buildExportQuery builds a query for every album matching the options'
+// filters, in their sort order. Pagination options are ignored. Price points
+// are aggregated into a JSON array per row so the rows can be streamed.
+func buildExportQuery(tenant string, opts ListOptions) (string, []interface{}) {
+	where, args := filterClause(tenant, opts)
+	col := sortColumns[opts.Sort]
+
+	direction := "ASC"
+	if opts.Desc {
+		direction = "DESC"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, title, artist, price, currency, version, created_at, updated_at, deleted_at,
+			COALESCE((
+				SELECT json_agg(json_build_object('currency', p.currency, 'amount', p.amount) ORDER BY p.currency)
+				FROM album_prices p
+				WHERE p.album_id = albums.id
+			), '[]')
+		FROM albums
+		WHERE %s
+		ORDER BY %s %s, id %s`, where, col.column, direction, direction)
+
+	return query, args
+}
+
+// escapeLike escapes LIKE wildcards so user input matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
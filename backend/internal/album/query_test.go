@@ -0,0 +1,118 @@
+package album
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListOptions_Normalize(t *testing.T) {
+	opts := ListOptions{}
+	require.NoError(t, opts.normalize())
+	assert.Equal(t, DefaultLimit, opts.Limit)
+	assert.Equal(t, "id", opts.Sort)
+
+	opts = ListOptions{Limit: MaxLimit + 1}
+	require.NoError(t, opts.normalize())
+	assert.Equal(t, MaxLimit, opts.Limit)
+
+	opts = ListOptions{Sort: "price; DROP TABLE albums"}
+	assert.ErrorIs(t, opts.normalize(), ErrInvalidQuery)
+
+	min, max := Money(2000), Money(1000)
+	opts = ListOptions{MinPrice: &min, MaxPrice: &max}
+	assert.ErrorIs(t, opts.normalize(), ErrInvalidQuery)
+
+	opts = ListOptions{Cursor: "abc", Offset: 10}
+	assert.ErrorIs(t, opts.normalize(), ErrInvalidQuery)
+}
+
+func TestCursor_RoundTrip(t *testing.T) {
+	created := time.Date(2025, 10, 15, 12, 30, 45, 123456000, time.UTC)
+	a := Album{ID: 42, Title: "Blue Train", Price: 1999, CreatedAt: created}
+
+	encoded := encodeCursor(a, "created_at", true, false)
+	decoded, err := decodeCursor(encoded, "created_at", true)
+	require.NoError(t, err)
+
+	assert.Equal(t, 42, decoded.ID)
+	assert.Equal(t, "2025-10-15T12:30:45.123456", decoded.Value)
+	assert.False(t, decoded.Prev)
+}
+
+func TestCursor_Mismatch(t *testing.T) {
+	encoded := encodeCursor(Album{ID: 1, Price: 999}, "price", false, false)
+
+	_, err := decodeCursor(encoded, "title", false)
+	assert.ErrorIs(t, err, ErrInvalidQuery)
+
+	_, err = decodeCursor("not a cursor!", "price", false)
+	assert.ErrorIs(t, err, ErrInvalidQuery)
+}
+
+// rated is the exchange-rate time used by price filter tests
+var rated = time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+
+func TestBuildListQuery(t *testing.T) {
+	min := Money(1000)
+	opts := ListOptions{Limit: 20, Artist: "John Coltrane", TitleContains: "50%", MinPrice: &min, PriceCurrency: "USD", RatesAt: rated, Sort: "price", Desc: true}
+
+	query, args := buildListQuery("acme", opts, nil)
+	assert.Contains(t, query, "tenant_id = $1")
+	assert.Contains(t, query, "LOWER(artist) = LOWER($2)")
+	assert.Contains(t, query, "title ILIKE $3")
+	assert.Contains(t, query, "CASE WHEN albums.currency = $4 THEN albums.price END")
+	assert.Contains(t, query, "r.effective_at <= $5")
+	assert.Contains(t, query, "LIMIT 1)) >= $6")
+	assert.Contains(t, query, "ORDER BY price DESC, id DESC")
+	assert.Equal(t, []interface{}{"acme", "John Coltrane", `%50\%%`, "USD", rated, Money(1000), 21}, args)
+
+	// A prev cursor walks backwards from the boundary row
+	query, args = buildListQuery("acme", opts, &cursor{Sort: "price", Desc: true, Value: "12.5", ID: 7, Prev: true})
+	assert.Contains(t, query, "(price, id) > ($7::numeric, $8)")
+	assert.Contains(t, query, "ORDER BY price ASC, id ASC")
+	assert.Equal(t, []interface{}{"acme", "John Coltrane", `%50\%%`, "USD", rated, Money(1000), "12.5", 7, 21}, args)
+}
+
+func TestListOptions_PriceCurrency(t *testing.T) {
+	// Price bounds are in the default currency unless one is named
+	min := Money(1000)
+	opts := ListOptions{MinPrice: &min}
+	require.NoError(t, opts.normalize())
+	assert.Equal(t, DefaultCurrency, opts.PriceCurrency)
+	assert.False(t, opts.RatesAt.IsZero())
+
+	opts = ListOptions{MinPrice: &min, PriceCurrency: "eur"}
+	require.NoError(t, opts.normalize())
+	assert.Equal(t, "EUR", opts.PriceCurrency)
+
+	opts = ListOptions{MaxPrice: &min, PriceCurrency: "euro"}
+	assert.ErrorIs(t, opts.normalize(), ErrInvalidQuery)
+
+	// Both bounds compare the same converted price
+	where, args := filterClause("acme", ListOptions{MinPrice: &min, MaxPrice: &min, PriceCurrency: "EUR", RatesAt: rated})
+	assert.Contains(t, where, "LIMIT 1)) >= $4")
+	assert.Contains(t, where, "LIMIT 1)) <= $5")
+	assert.Equal(t, []interface{}{"acme", "EUR", rated, Money(1000), Money(1000)}, args)
+}
+
+func TestBuildListQuery_Search(t *testing.T) {
+	query, args := buildListQuery("acme", ListOptions{Limit: 5, Search: "coltrane", Sort: "id"}, nil)
+	assert.Contains(t, query, "(title ILIKE $2 OR artist ILIKE $2)")
+	assert.Equal(t, []interface{}{"acme", "%coltrane%", 6}, args)
+}
+
+func TestBuildExportQuery(t *testing.T) {
+	query, args := buildExportQuery("acme", ListOptions{Artist: "John Coltrane", Sort: "title", Limit: 5, Offset: 10})
+	assert.Contains(t, query, "tenant_id = $1 AND deleted_at IS NULL AND LOWER(artist) = LOWER($2)")
+	assert.Contains(t, query, "ORDER BY title ASC, id ASC")
+	assert.NotContains(t, query, "LIMIT")
+	assert.NotContains(t, query, "OFFSET")
+	assert.Equal(t, []interface{}{"acme", "John Coltrane"}, args)
+
+	query, _ = buildExportQuery("acme", ListOptions{IncludeDeleted: true, Sort: "id", Desc: true})
+	assert.Contains(t, query, "WHERE tenant_id = $1\n")
+	assert.Contains(t, query, "ORDER BY id DESC, id DESC")
+}
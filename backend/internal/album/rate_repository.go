@@ -0,0 +1,99 @@
+package album
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RateRepository handles exchange rate data access
+type RateRepository interface {
+	Create(ctx context.Context, rate *ExchangeRate) error
+	List(ctx context.Context, base, quote string) ([]ExchangeRate, error)
+	FindRate(ctx context.Context, base, quote string, at time.Time) (*ExchangeRate, error)
+}
+
+// rateRepository implements RateRepository
+type rateRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewRateRepository creates a new exchange rate repository
+func NewRateRepository(pool *pgxpool.Pool) RateRepository {
+	return &rateRepository{pool: pool}
+}
+
+// Create records an exchange rate, replacing any rate for the same pair and effective time
+func (r *rateRepository) Create(ctx context.Context, rate *ExchangeRate) error {
+	query := `
+		INSERT INTO exchange_rates (base_currency, quote_currency, rate, effective_at, created_at)
+		VALUES ($1, $2, $3::numeric, $4, $5)
+		ON CONFLICT (base_currency, quote_currency, effective_at)
+		DO UPDATE SET rate = EXCLUDED.rate, created_at = EXCLUDED.created_at
+		RETURNING id, created_at
+	`
+
+	return r.pool.QueryRow(ctx, query, rate.Base, rate.Quote, rate.Rate, rate.EffectiveAt, time.Now()).
+		Scan(&rate.ID, &rate.CreatedAt)
+}
+
+// List retrieves exchange rates, newest first, optionally for one base and/or quote currency
+func (r *rateRepository) List(ctx context.Context, base, quote string) ([]ExchangeRate, error) {
+	query := `
+		SELECT id, base_currency, quote_currency, rate::text, effective_at, created_at
+		FROM exchange_rates
+		WHERE ($1 = '' OR base_currency = $1) AND ($2 = '' OR quote_currency = $2)
+		ORDER BY base_currency, quote_currency, effective_at DESC
+	`
+
+	rows, err := r.pool.Query(ctx, query, base, quote)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := make([]ExchangeRate, 0)
+	for rows.Next() {
+		var rate ExchangeRate
+		if err := rows.Scan(&rate.ID, &rate.Base, &rate.Quote, &rate.Rate, &rate.EffectiveAt, &rate.CreatedAt); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rates, nil
+}
+
+// FindRate retrieves the rate in effect at the given time for converting base
+// into quote. A rate stored the other way round (quote into base) is returned
+// when no direct rate exists; callers invert it.
+func (r *rateRepository) FindRate(ctx context.Context, base, quote string, at time.Time) (*ExchangeRate, error) {
+	query := `
+		SELECT id, base_currency, quote_currency, rate::text, effective_at, created_at
+		FROM exchange_rates
+		WHERE ((base_currency = $1 AND quote_currency = $2) OR (base_currency = $2 AND quote_currency = $1))
+			AND effective_at <= $3
+		ORDER BY base_currency = $1 DESC, effective_at DESC
+		LIMIT 1
+	`
+
+	var rate ExchangeRate
+	err := r.pool.QueryRow(ctx, query, base, quote, at).
+		Scan(&rate.ID, &rate.Base, &rate.Quote, &rate.Rate, &rate.EffectiveAt, &rate.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w from %s to %s", ErrNoExchangeRate, base, quote)
+		}
+		return nil, err
+	}
+
+	return &rate, nil
+}
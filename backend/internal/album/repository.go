@@ -2,148 +2,643 @@ package album
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"time"
 
+	"web-service-gin/backend/internal/middleware"
+
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var (
 	// ErrNotFound is returned when a record is not found
 	ErrNotFound = errors.New("record not found")
+	// ErrVersionConflict is returned when a record changed since it was read
+	ErrVersionConflict = errors.New("version conflict")
+	// ErrRevisionNotFound is returned when an album has no such revision
+	ErrRevisionNotFound = errors.New("revision not found")
 )
 
 // Repository handles album data access
 type Repository interface {
 	FindAll(ctx context.Context) ([]Album, error)
+	List(ctx context.Context, opts ListOptions) (*ListResult, error)
+	Export(ctx context.Context, opts ListOptions, fn func(*Album) error) error
 	FindByID(ctx context.Context, id int) (*Album, error)
 	Create(ctx context.Context, album *Album) error
+	Import(ctx context.Context, albums []Album) (int64, error)
 	Update(ctx context.Context, album *Album) error
-	Delete(ctx context.Context, id int) error
+	Delete(ctx context.Context, id int, version int) error
+	Restore(ctx context.Context, id int) (*Album, error)
+	Purge(ctx context.Context, id int) error
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+	History(ctx context.Context, albumID int) ([]Revision, error)
+	Revert(ctx context.Context, albumID int, revisionID int64, version int) (*Album, error)
+	WithinTx(ctx context.Context, fn func(repo Repository) error) error
+}
+
+// dbtx is the subset of pgxpool.Pool and pgx.Tx the repository uses, so the
+// same queries run either on the pool or inside a transaction. Begin on a
+// pgx.Tx starts a savepoint.
+type dbtx interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
 }
 
-// repository implements Repository
+// repository implements Repository. Every method is confined to the tenant
+// on its context (see middleware.TenantFrom), both by its own queries and by
+// the row-level security policies on the album tables.
 type repository struct {
-	pool *pgxpool.Pool
+	db dbtx
 }
 
 // NewRepository creates a new album repository
 func NewRepository(pool *pgxpool.Pool) Repository {
-	return &repository{pool: pool}
+	return &repository{db: pool}
+}
+
+// NewTxRepository creates an album repository whose statements run in tx, so
+// other packages can make album changes part of their own transactions
+func NewTxRepository(tx pgx.Tx) Repository {
+	return &repository{db: tx}
+}
+
+// WithinTx runs fn with a repository bound to a new transaction. The
+// transaction commits if fn returns nil and rolls back otherwise, so the
+// calls fn makes either all take effect or none do.
+func (r *repository) WithinTx(ctx context.Context, fn func(repo Repository) error) error {
+	return r.scoped(ctx, func(repo *repository) error {
+		return fn(repo)
+	})
+}
+
+// begin starts a transaction (or savepoint) and sets app.tenant_id, which the
+// row-level security policies read, to the tenant on ctx for its duration
+func (r *repository) begin(ctx context.Context) (pgx.Tx, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", middleware.TenantFrom(ctx)); err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// scoped runs fn with a repository bound to a transaction scoped to the
+// tenant on ctx, committing if fn returns nil
+func (r *repository) scoped(ctx context.Context, fn func(repo *repository) error) error {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&repository{db: tx}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
 }
 
 // FindAll retrieves all albums (excluding soft-deleted)
 func (r *repository) FindAll(ctx context.Context) ([]Album, error) {
 	query := `
-		SELECT id, title, artist, price, created_at, updated_at, deleted_at
+		SELECT id, title, artist, price, currency, version, created_at, updated_at, deleted_at
 		FROM albums
-		WHERE deleted_at IS NULL
+		WHERE tenant_id = $1 AND deleted_at IS NULL
 		ORDER BY id
 	`
 
-	rows, err := r.pool.Query(ctx, query)
+	var albums []Album
+	err := r.scoped(ctx, func(repo *repository) error {
+		rows, err := repo.db.Query(ctx, query, middleware.TenantFrom(ctx))
+		if err != nil {
+			return err
+		}
+
+		if albums, err = scanAlbums(rows); err != nil {
+			return err
+		}
+
+		return repo.loadPrices(ctx, albums)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return albums, nil
+}
+
+// List retrieves a filtered, sorted page of albums (excluding soft-deleted)
+func (r *repository) List(ctx context.Context, opts ListOptions) (*ListResult, error) {
+	var result *ListResult
+	err := r.scoped(ctx, func(repo *repository) error {
+		var err error
+		result, err = repo.list(ctx, opts)
+		return err
+	})
+	return result, err
+}
+
+func (r *repository) list(ctx context.Context, opts ListOptions) (*ListResult, error) {
+	if err := opts.normalize(); err != nil {
+		return nil, err
+	}
+
+	var cur *cursor
+	if opts.Cursor != "" {
+		var err error
+		cur, err = decodeCursor(opts.Cursor, opts.Sort, opts.Desc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Count matching rows independently of the page position
+	tenant := middleware.TenantFrom(ctx)
+	where, args := filterClause(tenant, opts)
+	var total int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM albums WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	query, args := buildListQuery(tenant, opts, cur)
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
+
+	albums, err := scanAlbums(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(albums) > opts.Limit
+	if hasMore {
+		albums = albums[:opts.Limit]
+	}
+
+	if err := r.loadPrices(ctx, albums); err != nil {
+		return nil, err
+	}
+
+	result := &ListResult{Albums: albums, Total: total, Limit: opts.Limit}
+	if len(albums) == 0 {
+		return result, nil
+	}
+
+	backward := cur != nil && cur.Prev
+	if backward {
+		// Rows were fetched in reverse order; restore the requested order
+		for i, j := 0, len(albums)-1; i < j; i, j = i+1, j-1 {
+			albums[i], albums[j] = albums[j], albums[i]
+		}
+	}
+
+	first, last := albums[0], albums[len(albums)-1]
+	if backward {
+		result.NextCursor = encodeCursor(last, opts.Sort, opts.Desc, false)
+		if hasMore {
+			result.PrevCursor = encodeCursor(first, opts.Sort, opts.Desc, true)
+		}
+		return result, nil
+	}
+
+	if hasMore {
+		result.NextCursor = encodeCursor(last, opts.Sort, opts.Desc, false)
+	}
+	if cur != nil || opts.Offset > 0 {
+		result.PrevCursor = encodeCursor(first, opts.Sort, opts.Desc, true)
+	}
+
+	return result, nil
+}
+
+// Export streams every album matching the options' filters to fn, one row
+// at a time as it arrives from the database. It stops at the first error
+// returned by fn.
+func (r *repository) Export(ctx context.Context, opts ListOptions, fn func(*Album) error) error {
+	if err := opts.normalize(); err != nil {
+		return err
+	}
+
+	return r.scoped(ctx, func(repo *repository) error {
+		return repo.export(ctx, opts, fn)
+	})
+}
+
+func (r *repository) export(ctx context.Context, opts ListOptions, fn func(*Album) error) error {
+	query, args := buildExportQuery(middleware.TenantFrom(ctx), opts)
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
 	defer rows.Close()
 
-	var albums []Album
 	for rows.Next() {
 		var album Album
+		var prices []byte
 		err := rows.Scan(
 			&album.ID,
 			&album.Title,
 			&album.Artist,
 			&album.Price,
+			&album.Currency,
+			&album.Version,
 			&album.CreatedAt,
 			&album.UpdatedAt,
 			&album.DeletedAt,
+			&prices,
 		)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if err := json.Unmarshal(prices, &album.Prices); err != nil {
+			return err
 		}
-		albums = append(albums, album)
-	}
 
-	if err := rows.Err(); err != nil {
-		return nil, err
+		if err := fn(&album); err != nil {
+			return err
+		}
 	}
 
-	return albums, nil
+	return rows.Err()
 }
 
 // FindByID retrieves a single album by ID
 func (r *repository) FindByID(ctx context.Context, id int) (*Album, error) {
 	query := `
-		SELECT id, title, artist, price, created_at, updated_at, deleted_at
+		SELECT id, title, artist, price, currency, version, created_at, updated_at, deleted_at
 		FROM albums
-		WHERE id = $1 AND deleted_at IS NULL
+		WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
 	`
 
-	var album Album
-	err := r.pool.QueryRow(ctx, query, id).Scan(
-		&album.ID,
-		&album.Title,
-		&album.Artist,
-		&album.Price,
-		&album.CreatedAt,
-		&album.UpdatedAt,
-		&album.DeletedAt,
-	)
+	albums := make([]Album, 1)
+	err := r.scoped(ctx, func(repo *repository) error {
+		album := &albums[0]
+		err := repo.db.QueryRow(ctx, query, id, middleware.TenantFrom(ctx)).Scan(
+			&album.ID,
+			&album.Title,
+			&album.Artist,
+			&album.Price,
+			&album.Currency,
+			&album.Version,
+			&album.CreatedAt,
+			&album.UpdatedAt,
+			&album.DeletedAt,
+		)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return err
+		}
 
+		return repo.loadPrices(ctx, albums)
+	})
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrNotFound
-		}
 		return nil, err
 	}
 
-	return &album, nil
+	return &albums[0], nil
 }
 
-// Create creates a new album
+// Create creates a new album with its price points
 func (r *repository) Create(ctx context.Context, album *Album) error {
+	if album.Currency == "" {
+		album.Currency = DefaultCurrency
+	}
+
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
 	query := `
-		INSERT INTO albums (title, artist, price, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, created_at, updated_at
+		INSERT INTO albums (tenant_id, title, artist, price, currency, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, version, created_at, updated_at
 	`
 
 	now := time.Now()
-	err := r.pool.QueryRow(
+	err = tx.QueryRow(
 		ctx,
 		query,
+		middleware.TenantFrom(ctx),
 		album.Title,
 		album.Artist,
 		album.Price,
+		album.Currency,
 		now,
 		now,
-	).Scan(&album.ID, &album.CreatedAt, &album.UpdatedAt)
+	).Scan(&album.ID, &album.Version, &album.CreatedAt, &album.UpdatedAt)
+	if err != nil {
+		return err
+	}
 
-	return err
+	if err := replacePrices(ctx, tx, album.ID, album.Prices); err != nil {
+		return err
+	}
+
+	if err := recordRevision(ctx, tx, ActionCreate, nil, album, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Import bulk-inserts albums in a single transaction, so either every album
+// is inserted or none are. IDs are reserved up front so that a create
+// revision can be inserted for each album. The rows are sent as arrays and
+// unnested, since COPY is not allowed on tables with row-level security.
+// Price points are not imported.
+func (r *repository) Import(ctx context.Context, albums []Album) (int64, error) {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT nextval(pg_get_serial_sequence('albums', 'id'))
+		FROM generate_series(1, $1)
+	`, len(albums))
+	if err != nil {
+		return 0, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return 0, err
+	}
+
+	// Postgres keeps microseconds; match it so the snapshots agree with the rows
+	now := time.Now().Truncate(time.Microsecond)
+	titles := make([]string, len(albums))
+	artists := make([]string, len(albums))
+	prices := make([]string, len(albums))
+	currencies := make([]string, len(albums))
+	snapshots := make([]string, len(albums))
+	for i := range albums {
+		a := &albums[i]
+		a.ID, a.Version, a.CreatedAt, a.UpdatedAt, a.Prices = ids[i], 1, now, now, nil
+		if a.Currency == "" {
+			a.Currency = DefaultCurrency
+		}
+
+		after, err := snapshot(a)
+		if err != nil {
+			return 0, err
+		}
+		titles[i], artists[i], prices[i], currencies[i], snapshots[i] = a.Title, a.Artist, a.Price.String(), a.Currency, string(after)
+	}
+
+	tenant := middleware.TenantFrom(ctx)
+	result, err := tx.Exec(ctx, `
+		INSERT INTO albums (id, tenant_id, title, artist, price, currency, created_at, updated_at)
+		SELECT id, $1, title, artist, price::numeric, currency, $7, $7
+		FROM unnest($2::int[], $3::text[], $4::text[], $5::text[], $6::text[]) AS t(id, title, artist, price, currency)
+	`, tenant, ids, titles, artists, prices, currencies, now)
+	if err != nil {
+		return 0, err
+	}
+
+	actor := ActorFrom(ctx)
+	_, err = tx.Exec(ctx, `
+		INSERT INTO album_revisions (tenant_id, album_id, action, after, actor, source, created_at)
+		SELECT $1, album_id, $4, after::jsonb, $5, $6, $7
+		FROM unnest($2::int[], $3::text[]) AS t(album_id, after)
+	`, tenant, ids, snapshots, ActionCreate, actor.Name, actor.Source, now)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
 }
 
-// Update updates an existing album
+// Update updates an existing album and replaces its price points if it is
+// still at album.Version, which is then incremented. A zero Version updates
+// unconditionally.
 func (r *repository) Update(ctx context.Context, album *Album) error {
+	if album.Currency == "" {
+		album.Currency = DefaultCurrency
+	}
+
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := lockAlbum(ctx, tx, album.ID, false)
+	if err != nil {
+		return err
+	}
+	if album.Version != 0 && album.Version != before.Version {
+		return ErrVersionConflict
+	}
+
+	if err := updateLocked(ctx, tx, before, album, ActionUpdate, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Delete deletes an album by ID (soft delete) if it is still at the given
+// version. A zero version deletes unconditionally.
+func (r *repository) Delete(ctx context.Context, id int, version int) error {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := lockAlbum(ctx, tx, id, false)
+	if err != nil {
+		return err
+	}
+	if version != 0 && version != before.Version {
+		return ErrVersionConflict
+	}
+
 	query := `
 		UPDATE albums
-		SET title = $1, artist = $2, price = $3, updated_at = $4
-		WHERE id = $5 AND deleted_at IS NULL
-		RETURNING updated_at
+		SET deleted_at = $1, version = version + 1
+		WHERE id = $2
+		RETURNING version, deleted_at
 	`
 
-	now := time.Now()
-	err := r.pool.QueryRow(
-		ctx,
-		query,
-		album.Title,
-		album.Artist,
-		album.Price,
-		now,
-		album.ID,
-	).Scan(&album.UpdatedAt)
+	after := *before
+	if err := tx.QueryRow(ctx, query, time.Now(), id).Scan(&after.Version, &after.DeletedAt); err != nil {
+		return err
+	}
+
+	if err := recordRevision(ctx, tx, ActionDelete, before, &after, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Restore undeletes a soft-deleted album
+func (r *repository) Restore(ctx context.Context, id int) (*Album, error) {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := lockAlbum(ctx, tx, id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE albums
+		SET deleted_at = NULL, updated_at = $1, version = version + 1
+		WHERE id = $2
+		RETURNING version, updated_at, deleted_at
+	`
+
+	album := *before
+	if err := tx.QueryRow(ctx, query, time.Now(), id).Scan(&album.Version, &album.UpdatedAt, &album.DeletedAt); err != nil {
+		return nil, err
+	}
+
+	if err := recordRevision(ctx, tx, ActionRestore, before, &album, nil); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return &album, nil
+}
+
+// History retrieves every recorded change to an album, newest first. It
+// includes changes to albums that have since been purged.
+func (r *repository) History(ctx context.Context, albumID int) ([]Revision, error) {
+	query := `
+		SELECT id, album_id, action, before, after, actor, source, reverted_from, created_at
+		FROM album_revisions
+		WHERE album_id = $1 AND tenant_id = $2
+		ORDER BY id DESC
+	`
+
+	revisions := make([]Revision, 0)
+	err := r.scoped(ctx, func(repo *repository) error {
+		rows, err := repo.db.Query(ctx, query, albumID, middleware.TenantFrom(ctx))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			revision, err := scanRevision(rows)
+			if err != nil {
+				return err
+			}
+			revisions = append(revisions, *revision)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
 
+// Revert sets an album's title, artist and pricing back to how they were
+// after the given revision, if the album is still at the given version. A
+// zero version reverts unconditionally. Whether the album is deleted is not
+// changed; use Restore for that.
+func (r *repository) Revert(ctx context.Context, albumID int, revisionID int64, version int) (*Album, error) {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT id, album_id, action, before, after, actor, source, reverted_from, created_at
+		FROM album_revisions
+		WHERE id = $1 AND album_id = $2 AND tenant_id = $3
+	`
+
+	revision, err := scanRevision(tx.QueryRow(ctx, query, revisionID, albumID, middleware.TenantFrom(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, err
+	}
+
+	target := revision.After
+	if target == nil {
+		target = revision.Before
+	}
+	if target == nil {
+		return nil, ErrRevisionNotFound
+	}
+
+	before, err := lockAlbum(ctx, tx, albumID, false)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && version != before.Version {
+		return nil, ErrVersionConflict
+	}
+
+	album := *before
+	album.Title = target.Title
+	album.Artist = target.Artist
+	album.Price = target.Price
+	album.Currency = target.Currency
+	album.Prices = target.Prices
+	if album.Currency == "" {
+		album.Currency = DefaultCurrency
+	}
+
+	if err := updateLocked(ctx, tx, before, &album, ActionRevert, &revision.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return &album, nil
+}
+
+// Purge permanently deletes an album, whether or not it is soft-deleted,
+// and records who purged it
+func (r *repository) Purge(ctx context.Context, id int) error {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var deleted bool
+	err = tx.QueryRow(ctx, "SELECT deleted_at IS NOT NULL FROM albums WHERE id = $1 AND tenant_id = $2 FOR UPDATE",
+		id, middleware.TenantFrom(ctx)).Scan(&deleted)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return ErrNotFound
@@ -151,25 +646,294 @@ func (r *repository) Update(ctx context.Context, album *Album) error {
 		return err
 	}
 
-	return nil
+	if err := purgeLocked(ctx, tx, id, deleted); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// PurgeDeleted permanently deletes albums soft-deleted before the given
+// time. Retention applies to every tenant, so this is the one method that
+// is not confined to the tenant on ctx. Each purge is recorded in its
+// album's tenant as a change made by the system.
+func (r *repository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.all_tenants', 'on', true)"); err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.Query(ctx, "SELECT id, tenant_id FROM albums WHERE deleted_at < $1 ORDER BY id FOR UPDATE", before)
+	if err != nil {
+		return 0, err
+	}
+	type expired struct {
+		id     int
+		tenant string
+	}
+	var albums []expired
+	for rows.Next() {
+		var a expired
+		if err := rows.Scan(&a.id, &a.tenant); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		albums = append(albums, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	ctx = WithActor(ctx, Actor{Name: SourceSystem, Source: SourceSystem})
+	for _, a := range albums {
+		// Revisions may only be written in the tenant set for the transaction
+		if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", a.tenant); err != nil {
+			return 0, err
+		}
+		if err := purgeLocked(middleware.WithTenant(ctx, a.tenant), tx, a.id, true); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return int64(len(albums)), nil
+}
+
+// purgeLocked deletes an album whose row the transaction has locked and
+// records the purge in its history
+func purgeLocked(ctx context.Context, tx pgx.Tx, id int, deleted bool) error {
+	before, err := lockAlbum(ctx, tx, id, deleted)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM albums WHERE id = $1", id); err != nil {
+		return err
+	}
+
+	return recordRevision(ctx, tx, ActionPurge, before, nil, nil)
+}
+
+// lockAlbum reads an album with its price points and locks its row until
+// the transaction ends. deleted selects whether the album must be in the
+// trash or live.
+func lockAlbum(ctx context.Context, tx pgx.Tx, id int, deleted bool) (*Album, error) {
+	query := `
+		SELECT id, title, artist, price, currency, version, created_at, updated_at, deleted_at
+		FROM albums
+		WHERE id = $1 AND tenant_id = $2 AND (deleted_at IS NOT NULL) = $3
+		FOR UPDATE
+	`
+
+	var album Album
+	err := tx.QueryRow(ctx, query, id, middleware.TenantFrom(ctx), deleted).Scan(
+		&album.ID,
+		&album.Title,
+		&album.Artist,
+		&album.Price,
+		&album.Currency,
+		&album.Version,
+		&album.CreatedAt,
+		&album.UpdatedAt,
+		&album.DeletedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	albums := []Album{album}
+	if err := (&repository{db: tx}).loadPrices(ctx, albums); err != nil {
+		return nil, err
+	}
+
+	return &albums[0], nil
 }
 
-// Delete deletes an album by ID (soft delete)
-func (r *repository) Delete(ctx context.Context, id int) error {
+// updateLocked writes album over the locked row read as before, replaces its
+// price points and records the change
+func updateLocked(ctx context.Context, tx pgx.Tx, before, album *Album, action string, revertedFrom *int64) error {
 	query := `
 		UPDATE albums
-		SET deleted_at = $1
-		WHERE id = $2 AND deleted_at IS NULL
+		SET title = $1, artist = $2, price = $3, currency = $4, updated_at = $5, version = version + 1
+		WHERE id = $6
+		RETURNING version, created_at, updated_at, deleted_at
 	`
 
-	result, err := r.pool.Exec(ctx, query, time.Now(), id)
+	err := tx.QueryRow(
+		ctx,
+		query,
+		album.Title,
+		album.Artist,
+		album.Price,
+		album.Currency,
+		time.Now(),
+		album.ID,
+	).Scan(&album.Version, &album.CreatedAt, &album.UpdatedAt, &album.DeletedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := replacePrices(ctx, tx, album.ID, album.Prices); err != nil {
+		return err
+	}
+
+	return recordRevision(ctx, tx, action, before, album, revertedFrom)
+}
+
+// recordRevision saves a change to an album, attributed to the actor on ctx
+func recordRevision(ctx context.Context, tx pgx.Tx, action string, before, after *Album, revertedFrom *int64) error {
+	beforeJSON, err := snapshot(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := snapshot(after)
 	if err != nil {
 		return err
 	}
 
-	if result.RowsAffected() == 0 {
-		return ErrNotFound
+	var albumID int
+	if after != nil {
+		albumID = after.ID
+	} else {
+		albumID = before.ID
+	}
+
+	actor := ActorFrom(ctx)
+	_, err = tx.Exec(ctx, `
+		INSERT INTO album_revisions (tenant_id, album_id, action, before, after, actor, source, reverted_from, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, middleware.TenantFrom(ctx), albumID, action, beforeJSON, afterJSON, actor.Name, actor.Source, revertedFrom, time.Now())
+	return err
+}
+
+// scanRevision reads one album_revisions row
+func scanRevision(row pgx.Row) (*Revision, error) {
+	var revision Revision
+	var before, after []byte
+	err := row.Scan(
+		&revision.ID,
+		&revision.AlbumID,
+		&revision.Action,
+		&before,
+		&after,
+		&revision.Actor,
+		&revision.Source,
+		&revision.RevertedFrom,
+		&revision.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if before != nil {
+		if err := json.Unmarshal(before, &revision.Before); err != nil {
+			return nil, err
+		}
+	}
+	if after != nil {
+		if err := json.Unmarshal(after, &revision.After); err != nil {
+			return nil, err
+		}
+	}
+
+	return &revision, nil
+}
+
+// loadPrices fills in the price points of the given albums
+func (r *repository) loadPrices(ctx context.Context, albums []Album) error {
+	if len(albums) == 0 {
+		return nil
+	}
+
+	index := make(map[int]*Album, len(albums))
+	ids := make([]int, 0, len(albums))
+	for i := range albums {
+		index[albums[i].ID] = &albums[i]
+		ids = append(ids, albums[i].ID)
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT album_id, currency, amount
+		FROM album_prices
+		WHERE album_id = ANY($1)
+		ORDER BY album_id, currency
+	`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var albumID int
+		var point PricePoint
+		if err := rows.Scan(&albumID, &point.Currency, &point.Amount); err != nil {
+			return err
+		}
+		if album, ok := index[albumID]; ok {
+			album.Prices = append(album.Prices, point)
+		}
+	}
+
+	return rows.Err()
+}
+
+// replacePrices swaps an album's price points for the given ones
+func replacePrices(ctx context.Context, tx pgx.Tx, albumID int, prices []PricePoint) error {
+	if _, err := tx.Exec(ctx, "DELETE FROM album_prices WHERE album_id = $1", albumID); err != nil {
+		return err
+	}
+
+	for _, point := range prices {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO album_prices (album_id, currency, amount)
+			VALUES ($1, $2, $3)
+		`, albumID, point.Currency, point.Amount)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
+
+// scanAlbums reads every row of an album query and closes the rows
+func scanAlbums(rows pgx.Rows) ([]Album, error) {
+	defer rows.Close()
+
+	albums := make([]Album, 0)
+	for rows.Next() {
+		var album Album
+		err := rows.Scan(
+			&album.ID,
+			&album.Title,
+			&album.Artist,
+			&album.Price,
+			&album.Currency,
+			&album.Version,
+			&album.CreatedAt,
+			&album.UpdatedAt,
+			&album.DeletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		albums = append(albums, album)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return albums, nil
+}
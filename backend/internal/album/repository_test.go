@@ -2,8 +2,14 @@ package album
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
+	"web-service-gin/backend/internal/middleware"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -33,7 +39,7 @@ func setupTestDB(t *testing.T) *pgxpool.Pool {
 // Helper function to clean up test database
 func cleanupTestDB(t *testing.T, pool *pgxpool.Pool) {
 	if pool != nil {
-		_, err := pool.Exec(context.Background(), "DELETE FROM albums")
+		_, err := pool.Exec(context.Background(), "DELETE FROM albums; DELETE FROM album_revisions; DELETE FROM exchange_rates")
 		if err != nil {
 			t.Logf("Warning: Failed to cleanup test data: %v", err)
 		}
@@ -49,10 +55,44 @@ func createTestTable(t *testing.T, pool *pgxpool.Pool) {
 		title VARCHAR(255) NOT NULL,
 		artist VARCHAR(255) NOT NULL,
 		price DECIMAL(10, 2) NOT NULL,
+		currency CHAR(3) NOT NULL DEFAULT 'USD',
+		version INT NOT NULL DEFAULT 1,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		deleted_at TIMESTAMP
 	);
+
+	CREATE TABLE IF NOT EXISTS album_prices (
+		album_id INT NOT NULL REFERENCES albums(id) ON DELETE CASCADE,
+		currency CHAR(3) NOT NULL,
+		amount DECIMAL(10, 2) NOT NULL CHECK (amount >= 0),
+		PRIMARY KEY (album_id, currency)
+	);
+
+	CREATE TABLE IF NOT EXISTS exchange_rates (
+		id SERIAL PRIMARY KEY,
+		base_currency CHAR(3) NOT NULL,
+		quote_currency CHAR(3) NOT NULL,
+		rate NUMERIC(18, 8) NOT NULL CHECK (rate > 0),
+		effective_at TIMESTAMP NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (base_currency, quote_currency, effective_at)
+	);
+
+	CREATE TABLE IF NOT EXISTS album_revisions (
+		id BIGSERIAL PRIMARY KEY,
+		album_id INT NOT NULL,
+		action VARCHAR(16) NOT NULL,
+		before JSONB,
+		after JSONB,
+		actor VARCHAR(255) NOT NULL,
+		source VARCHAR(16) NOT NULL,
+		reverted_from BIGINT REFERENCES album_revisions(id),
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	ALTER TABLE albums ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+	ALTER TABLE album_revisions ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
 	`
 	_, err := pool.Exec(context.Background(), query)
 	require.NoError(t, err)
@@ -71,7 +111,7 @@ func TestAlbumRepository_Create(t *testing.T) {
 	album := &Album{
 		Title:  "The Wall",
 		Artist: "Pink Floyd",
-		Price:  24.99,
+		Price:  2499,
 	}
 
 	err := repo.Create(context.Background(), album)
@@ -83,7 +123,7 @@ func TestAlbumRepository_Create(t *testing.T) {
 	assert.NotZero(t, album.UpdatedAt, "UpdatedAt should be set")
 	assert.Equal(t, "The Wall", album.Title)
 	assert.Equal(t, "Pink Floyd", album.Artist)
-	assert.Equal(t, 24.99, album.Price)
+	assert.Equal(t, Money(2499), album.Price)
 }
 
 func TestAlbumRepository_FindAll(t *testing.T) {
@@ -97,8 +137,8 @@ func TestAlbumRepository_FindAll(t *testing.T) {
 	repo := NewRepository(pool)
 
 	// Create test albums
-	album1 := &Album{Title: "The Wall", Artist: "Pink Floyd", Price: 24.99}
-	album2 := &Album{Title: "Dark Side of the Moon", Artist: "Pink Floyd", Price: 22.99}
+	album1 := &Album{Title: "The Wall", Artist: "Pink Floyd", Price: 2499}
+	album2 := &Album{Title: "Dark Side of the Moon", Artist: "Pink Floyd", Price: 2299}
 
 	require.NoError(t, repo.Create(context.Background(), album1))
 	require.NoError(t, repo.Create(context.Background(), album2))
@@ -112,6 +152,158 @@ func TestAlbumRepository_FindAll(t *testing.T) {
 	assert.Equal(t, "Dark Side of the Moon", albums[1].Title)
 }
 
+func TestAlbumRepository_List(t *testing.T) {
+	pool := setupTestDB(t)
+	if pool == nil {
+		return
+	}
+	defer cleanupTestDB(t, pool)
+
+	createTestTable(t, pool)
+	repo := NewRepository(pool)
+
+	// Create test albums
+	require.NoError(t, repo.Create(context.Background(), &Album{Title: "The Wall", Artist: "Pink Floyd", Price: 2499}))
+	require.NoError(t, repo.Create(context.Background(), &Album{Title: "Dark Side of the Moon", Artist: "Pink Floyd", Price: 2299}))
+	require.NoError(t, repo.Create(context.Background(), &Album{Title: "Blue Train", Artist: "John Coltrane", Price: 5699}))
+
+	// Filter by artist and sort by price descending
+	min := Money(2000)
+	result, err := repo.List(context.Background(), ListOptions{Artist: "pink floyd", MinPrice: &min, Sort: "price", Desc: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, result.Total)
+	require.Len(t, result.Albums, 2)
+	assert.Equal(t, "The Wall", result.Albums[0].Title)
+	assert.Equal(t, "Dark Side of the Moon", result.Albums[1].Title)
+
+	// Title substring matching is case-insensitive
+	result, err = repo.List(context.Background(), ListOptions{TitleContains: "TRAIN"})
+	require.NoError(t, err)
+	require.Len(t, result.Albums, 1)
+	assert.Equal(t, "Blue Train", result.Albums[0].Title)
+}
+
+func TestAlbumRepository_List_PriceCurrency(t *testing.T) {
+	pool := setupTestDB(t)
+	if pool == nil {
+		return
+	}
+	defer cleanupTestDB(t, pool)
+
+	createTestTable(t, pool)
+	repo := NewRepository(pool)
+	ctx := context.Background()
+
+	require.NoError(t, repo.Create(ctx, &Album{Title: "Blue Train", Artist: "John Coltrane", Price: 5699, Currency: "USD"}))
+	require.NoError(t, repo.Create(ctx, &Album{Title: "Kind of Blue", Artist: "Miles Davis", Price: 2000, Currency: "EUR"}))
+	require.NoError(t, repo.Create(ctx, &Album{Title: "Giant Steps", Artist: "John Coltrane", Price: 1799, Currency: "USD",
+		Prices: []PricePoint{{Currency: "EUR", Amount: 1500}}}))
+	require.NoError(t, NewRateRepository(pool).Create(ctx, &ExchangeRate{Base: "USD", Quote: "EUR", Rate: "0.925",
+		EffectiveAt: time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)}))
+
+	titles := func(result *ListResult) []string {
+		var titles []string
+		for _, a := range result.Albums {
+			titles = append(titles, a.Title)
+		}
+		return titles
+	}
+
+	// EUR bounds use the EUR price, the price point, then the converted price (52.72)
+	max := Money(3000)
+	result, err := repo.List(ctx, ListOptions{MaxPrice: &max, PriceCurrency: "EUR"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Kind of Blue", "Giant Steps"}, titles(result))
+
+	// Bounds default to USD, converting EUR prices with the inverted rate (21.62)
+	min := Money(2000)
+	result, err = repo.List(ctx, ListOptions{MinPrice: &min})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Blue Train", "Kind of Blue"}, titles(result))
+
+	// Albums without a price or rate in the currency never match
+	zero := Money(0)
+	result, err = repo.List(ctx, ListOptions{MinPrice: &zero, PriceCurrency: "GBP"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Total)
+}
+
+func TestAlbumRepository_List_Search(t *testing.T) {
+	pool := setupTestDB(t)
+	if pool == nil {
+		return
+	}
+	defer cleanupTestDB(t, pool)
+
+	createTestTable(t, pool)
+	repo := NewRepository(pool)
+
+	require.NoError(t, repo.Create(context.Background(), &Album{Title: "The Wall", Artist: "Pink Floyd", Price: 2499}))
+	require.NoError(t, repo.Create(context.Background(), &Album{Title: "Blue Train", Artist: "John Coltrane", Price: 5699}))
+	require.NoError(t, repo.Create(context.Background(), &Album{Title: "Giant Steps", Artist: "John Coltrane", Price: 1799}))
+
+	// Search matches artist as well as title
+	result, err := repo.List(context.Background(), ListOptions{Search: "COLTRANE"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Total)
+
+	// Search combines with price bounds and limits
+	max := Money(2000)
+	result, err = repo.List(context.Background(), ListOptions{Search: "coltrane", MaxPrice: &max, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Total)
+	require.Len(t, result.Albums, 1)
+	assert.Equal(t, "Giant Steps", result.Albums[0].Title)
+}
+
+func TestAlbumRepository_List_CursorPagination(t *testing.T) {
+	pool := setupTestDB(t)
+	if pool == nil {
+		return
+	}
+	defer cleanupTestDB(t, pool)
+
+	createTestTable(t, pool)
+	repo := NewRepository(pool)
+
+	for _, title := range []string{"A", "B", "C", "D", "E"} {
+		require.NoError(t, repo.Create(context.Background(), &Album{Title: title, Artist: "Test", Price: 999}))
+	}
+
+	// First page
+	page1, err := repo.List(context.Background(), ListOptions{Limit: 2, Sort: "title"})
+	require.NoError(t, err)
+	assert.Equal(t, 5, page1.Total)
+	require.Len(t, page1.Albums, 2)
+	assert.Equal(t, "A", page1.Albums[0].Title)
+	assert.NotEmpty(t, page1.NextCursor)
+	assert.Empty(t, page1.PrevCursor)
+
+	// Second page via next cursor
+	page2, err := repo.List(context.Background(), ListOptions{Limit: 2, Sort: "title", Cursor: page1.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, page2.Albums, 2)
+	assert.Equal(t, "C", page2.Albums[0].Title)
+	assert.Equal(t, "D", page2.Albums[1].Title)
+	assert.NotEmpty(t, page2.PrevCursor)
+
+	// Back to the first page via prev cursor
+	back, err := repo.List(context.Background(), ListOptions{Limit: 2, Sort: "title", Cursor: page2.PrevCursor})
+	require.NoError(t, err)
+	require.Len(t, back.Albums, 2)
+	assert.Equal(t, "A", back.Albums[0].Title)
+	assert.Equal(t, "B", back.Albums[1].Title)
+	assert.Empty(t, back.PrevCursor)
+
+	// Last page has no next cursor
+	page3, err := repo.List(context.Background(), ListOptions{Limit: 2, Sort: "title", Cursor: page2.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, page3.Albums, 1)
+	assert.Equal(t, "E", page3.Albums[0].Title)
+	assert.Empty(t, page3.NextCursor)
+}
+
 func TestAlbumRepository_FindByID(t *testing.T) {
 	pool := setupTestDB(t)
 	if pool == nil {
@@ -123,7 +315,7 @@ func TestAlbumRepository_FindByID(t *testing.T) {
 	repo := NewRepository(pool)
 
 	// Create a test album
-	album := &Album{Title: "The Wall", Artist: "Pink Floyd", Price: 24.99}
+	album := &Album{Title: "The Wall", Artist: "Pink Floyd", Price: 2499}
 	require.NoError(t, repo.Create(context.Background(), album))
 
 	// Find by ID
@@ -133,7 +325,7 @@ func TestAlbumRepository_FindByID(t *testing.T) {
 	assert.Equal(t, album.ID, found.ID)
 	assert.Equal(t, "The Wall", found.Title)
 	assert.Equal(t, "Pink Floyd", found.Artist)
-	assert.Equal(t, 24.99, found.Price)
+	assert.Equal(t, Money(2499), found.Price)
 }
 
 func TestAlbumRepository_FindByID_NotFound(t *testing.T) {
@@ -162,21 +354,55 @@ func TestAlbumRepository_Update(t *testing.T) {
 	repo := NewRepository(pool)
 
 	// Create a test album
-	album := &Album{Title: "The Wall", Artist: "Pink Floyd", Price: 24.99}
+	album := &Album{Title: "The Wall", Artist: "Pink Floyd", Price: 2499}
 	require.NoError(t, repo.Create(context.Background(), album))
 
 	// Update the album
-	album.Price = 29.99
+	album.Price = 2999
 	err := repo.Update(context.Background(), album)
 	require.NoError(t, err)
 
 	// Verify update
 	updated, err := repo.FindByID(context.Background(), album.ID)
 	require.NoError(t, err)
-	assert.Equal(t, 29.99, updated.Price)
+	assert.Equal(t, Money(2999), updated.Price)
+	assert.Equal(t, 2, updated.Version)
 	assert.True(t, updated.UpdatedAt.After(updated.CreatedAt), "UpdatedAt should be after CreatedAt")
 }
 
+func TestAlbumRepository_Update_VersionConflict(t *testing.T) {
+	pool := setupTestDB(t)
+	if pool == nil {
+		return
+	}
+	defer cleanupTestDB(t, pool)
+
+	createTestTable(t, pool)
+	repo := NewRepository(pool)
+
+	album := &Album{Title: "The Wall", Artist: "Pink Floyd", Price: 2499}
+	require.NoError(t, repo.Create(context.Background(), album))
+	assert.Equal(t, 1, album.Version)
+
+	// Two editors read the same version
+	first, err := repo.FindByID(context.Background(), album.ID)
+	require.NoError(t, err)
+	second, err := repo.FindByID(context.Background(), album.ID)
+	require.NoError(t, err)
+
+	first.Price = 2999
+	require.NoError(t, repo.Update(context.Background(), first))
+
+	// The second write is based on a stale version
+	second.Price = 1999
+	assert.Equal(t, ErrVersionConflict, repo.Update(context.Background(), second))
+	assert.Equal(t, ErrVersionConflict, repo.Delete(context.Background(), album.ID, second.Version))
+
+	current, err := repo.FindByID(context.Background(), album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, Money(2999), current.Price)
+}
+
 func TestAlbumRepository_Update_NotFound(t *testing.T) {
 	pool := setupTestDB(t)
 	if pool == nil {
@@ -188,7 +414,7 @@ func TestAlbumRepository_Update_NotFound(t *testing.T) {
 	repo := NewRepository(pool)
 
 	// Try to update non-existent album
-	album := &Album{ID: 99999, Title: "Test", Artist: "Test", Price: 9.99}
+	album := &Album{ID: 99999, Title: "Test", Artist: "Test", Price: 999}
 	err := repo.Update(context.Background(), album)
 	assert.Equal(t, ErrNotFound, err)
 }
@@ -204,11 +430,11 @@ func TestAlbumRepository_Delete(t *testing.T) {
 	repo := NewRepository(pool)
 
 	// Create a test album
-	album := &Album{Title: "The Wall", Artist: "Pink Floyd", Price: 24.99}
+	album := &Album{Title: "The Wall", Artist: "Pink Floyd", Price: 2499}
 	require.NoError(t, repo.Create(context.Background(), album))
 
 	// Delete the album (soft delete)
-	err := repo.Delete(context.Background(), album.ID)
+	err := repo.Delete(context.Background(), album.ID, album.Version)
 	require.NoError(t, err)
 
 	// Verify album is not found (soft deleted)
@@ -232,8 +458,361 @@ func TestAlbumRepository_Delete_NotFound(t *testing.T) {
 	repo := NewRepository(pool)
 
 	// Try to delete non-existent album
-	err := repo.Delete(context.Background(), 99999)
+	err := repo.Delete(context.Background(), 99999, 0)
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestAlbumRepository_TrashAndRestore(t *testing.T) {
+	pool := setupTestDB(t)
+	if pool == nil {
+		return
+	}
+	defer cleanupTestDB(t, pool)
+
+	createTestTable(t, pool)
+	repo := NewRepository(pool)
+	ctx := context.Background()
+
+	album := &Album{Title: "The Wall", Artist: "Pink Floyd", Price: 2499}
+	require.NoError(t, repo.Create(ctx, album))
+	require.NoError(t, repo.Delete(ctx, album.ID, 0))
+
+	trash, err := repo.List(ctx, ListOptions{Deleted: true})
+	require.NoError(t, err)
+	require.Len(t, trash.Albums, 1)
+	assert.NotNil(t, trash.Albums[0].DeletedAt)
+
+	restored, err := repo.Restore(ctx, album.ID)
+	require.NoError(t, err)
+	assert.Nil(t, restored.DeletedAt)
+
+	// Only albums in the trash can be restored
+	_, err = repo.Restore(ctx, album.ID)
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestAlbumRepository_Purge(t *testing.T) {
+	pool := setupTestDB(t)
+	if pool == nil {
+		return
+	}
+	defer cleanupTestDB(t, pool)
+
+	createTestTable(t, pool)
+	repo := NewRepository(pool)
+	ctx := context.Background()
+
+	old := &Album{Title: "The Wall", Artist: "Pink Floyd", Price: 2499}
+	recent := &Album{Title: "Animals", Artist: "Pink Floyd", Price: 1999}
+	live := &Album{Title: "Meddle", Artist: "Pink Floyd", Price: 1499}
+	for _, a := range []*Album{old, recent, live} {
+		require.NoError(t, repo.Create(ctx, a))
+	}
+	require.NoError(t, repo.Delete(ctx, old.ID, 0))
+	require.NoError(t, repo.Delete(ctx, recent.ID, 0))
+
+	_, err := pool.Exec(ctx, "UPDATE albums SET deleted_at = $1 WHERE id = $2", time.Now().Add(-48*time.Hour), old.ID)
+	require.NoError(t, err)
+
+	purged, err := repo.PurgeDeleted(ctx, time.Now().Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	// Hard deletes work on live albums too
+	admin := WithActor(ctx, Actor{Name: "root", Source: SourceREST})
+	require.NoError(t, repo.Purge(admin, live.ID))
+	assert.Equal(t, ErrNotFound, repo.Purge(ctx, old.ID))
+
+	// Each purge ends the album's history with who removed it
+	history, err := repo.History(ctx, old.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, ActionPurge, history[0].Action)
+	assert.Equal(t, SourceSystem, history[0].Actor)
+	assert.Equal(t, "The Wall", history[0].Before.Title)
+	assert.Nil(t, history[0].After)
+
+	history, err = repo.History(ctx, live.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, ActionPurge, history[0].Action)
+	assert.Equal(t, "root", history[0].Actor)
+	assert.Equal(t, SourceREST, history[0].Source)
+
+	trash, err := repo.List(ctx, ListOptions{Deleted: true})
+	require.NoError(t, err)
+	require.Len(t, trash.Albums, 1)
+	assert.Equal(t, recent.ID, trash.Albums[0].ID)
+}
+
+func TestAlbumRepository_TenantIsolation(t *testing.T) {
+	pool := setupTestDB(t)
+	if pool == nil {
+		return
+	}
+	defer cleanupTestDB(t, pool)
+
+	createTestTable(t, pool)
+	repo := NewRepository(pool)
+	acme := middleware.WithTenant(context.Background(), "acme")
+	globex := middleware.WithTenant(context.Background(), "globex")
+
+	album := &Album{Title: "The Wall", Artist: "Pink Floyd", Price: 2499}
+	require.NoError(t, repo.Create(acme, album))
+	_, err := repo.Import(acme, []Album{{Title: "Animals", Artist: "Pink Floyd", Price: 1999}})
+	require.NoError(t, err)
+
+	// Another tenant can neither see nor change the album
+	_, err = repo.FindByID(globex, album.ID)
+	assert.Equal(t, ErrNotFound, err)
+	assert.Equal(t, ErrNotFound, repo.Update(globex, &Album{ID: album.ID, Title: "Mine", Artist: "Pink Floyd", Price: 1}))
+	assert.Equal(t, ErrNotFound, repo.Delete(globex, album.ID, 0))
+	assert.Equal(t, ErrNotFound, repo.Purge(globex, album.ID))
+
+	history, err := repo.History(globex, album.ID)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	result, err := repo.List(globex, ListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Total)
+
+	result, err = repo.List(acme, ListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Total)
+
+	// Contexts without a tenant use the default one
+	result, err = repo.List(context.Background(), ListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Total)
+}
+
+func TestAlbumRepository_PricePoints(t *testing.T) {
+	pool := setupTestDB(t)
+	if pool == nil {
+		return
+	}
+	defer cleanupTestDB(t, pool)
+
+	createTestTable(t, pool)
+	repo := NewRepository(pool)
+	ctx := context.Background()
+
+	album := &Album{
+		Title:  "Abbey Road",
+		Artist: "The Beatles",
+		Price:  1999,
+		Prices: []PricePoint{{Currency: "EUR", Amount: 1899}},
+	}
+	require.NoError(t, repo.Create(ctx, album))
+	assert.Equal(t, DefaultCurrency, album.Currency)
+
+	found, err := repo.FindByID(ctx, album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []PricePoint{{Currency: "EUR", Amount: 1899}}, found.Prices)
+
+	// Updating replaces the price points
+	found.Prices = []PricePoint{{Currency: "GBP", Amount: 1699}}
+	require.NoError(t, repo.Update(ctx, found))
+
+	found, err = repo.FindByID(ctx, album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []PricePoint{{Currency: "GBP", Amount: 1699}}, found.Prices)
+}
+
+func TestAlbumRepository_Import(t *testing.T) {
+	pool := setupTestDB(t)
+	if pool == nil {
+		return
+	}
+	defer cleanupTestDB(t, pool)
+
+	createTestTable(t, pool)
+	repo := NewRepository(pool)
+	ctx := context.Background()
+
+	count, err := repo.Import(ctx, []Album{
+		{Title: "Blue Train", Artist: "John Coltrane", Price: 5699},
+		{Title: "Giant Steps", Artist: "John Coltrane", Price: 1799, Currency: "EUR"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	albums, err := repo.FindAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, albums, 2)
+	assert.Equal(t, Money(5699), albums[0].Price)
+	assert.Equal(t, DefaultCurrency, albums[0].Currency)
+	assert.Equal(t, "EUR", albums[1].Currency)
+	assert.Equal(t, 1, albums[1].Version)
+}
+
+func TestAlbumRepository_Import_Bulk(t *testing.T) {
+	pool := setupTestDB(t)
+	if pool == nil {
+		return
+	}
+	defer cleanupTestDB(t, pool)
+
+	createTestTable(t, pool)
+	repo := NewRepository(pool)
+	ctx := context.Background()
+
+	// A catalog-sized batch goes through the unnest INSERT in one statement
+	const n = 5000
+	albums := make([]Album, n)
+	for i := range albums {
+		albums[i] = Album{Title: fmt.Sprintf("Album %d", i), Artist: fmt.Sprintf("Artist %d", i%50), Price: Money(100 + i)}
+	}
+
+	count, err := repo.Import(ctx, albums)
+	require.NoError(t, err)
+	assert.Equal(t, int64(n), count)
+
+	seen := make(map[int]bool, n)
+	for _, a := range albums {
+		seen[a.ID] = true
+	}
+	assert.Len(t, seen, n)
+
+	result, err := repo.List(ctx, ListOptions{Sort: "price", Desc: true, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, n, result.Total)
+	assert.Equal(t, fmt.Sprintf("Album %d", n-1), result.Albums[0].Title)
+
+	var revisions int
+	require.NoError(t, pool.QueryRow(ctx, "SELECT COUNT(*) FROM album_revisions WHERE action = $1", ActionCreate).Scan(&revisions))
+	assert.Equal(t, n, revisions)
+}
+
+func TestAlbumRepository_Export(t *testing.T) {
+	pool := setupTestDB(t)
+	if pool == nil {
+		return
+	}
+	defer cleanupTestDB(t, pool)
+
+	createTestTable(t, pool)
+	repo := NewRepository(pool)
+	ctx := context.Background()
+
+	live := &Album{Title: "Blue Train", Artist: "John Coltrane", Price: 5699, Prices: []PricePoint{{Currency: "EUR", Amount: 5299}}}
+	deleted := &Album{Title: "Giant Steps", Artist: "John Coltrane", Price: 1799}
+	require.NoError(t, repo.Create(ctx, live))
+	require.NoError(t, repo.Create(ctx, deleted))
+	require.NoError(t, repo.Delete(ctx, deleted.ID, 0))
+
+	export := func(opts ListOptions) []Album {
+		var albums []Album
+		require.NoError(t, repo.Export(ctx, opts, func(a *Album) error {
+			albums = append(albums, *a)
+			return nil
+		}))
+		return albums
+	}
+
+	albums := export(ListOptions{})
+	require.Len(t, albums, 1)
+	assert.Equal(t, []PricePoint{{Currency: "EUR", Amount: 5299}}, albums[0].Prices)
+
+	albums = export(ListOptions{IncludeDeleted: true, Sort: "price"})
+	require.Len(t, albums, 2)
+	assert.Equal(t, deleted.ID, albums[0].ID)
+	assert.NotNil(t, albums[0].DeletedAt)
+}
+
+func TestAlbumRepository_WithinTx(t *testing.T) {
+	pool := setupTestDB(t)
+	if pool == nil {
+		return
+	}
+	defer cleanupTestDB(t, pool)
+
+	createTestTable(t, pool)
+	repo := NewRepository(pool)
+	ctx := context.Background()
+
+	existing := &Album{Title: "Blue Train", Artist: "John Coltrane", Price: 5699}
+	require.NoError(t, repo.Create(ctx, existing))
+
+	// A failing call rolls back everything done in the transaction
+	err := repo.WithinTx(ctx, func(tx Repository) error {
+		require.NoError(t, tx.Create(ctx, &Album{Title: "The Wall", Artist: "Pink Floyd", Price: 2499}))
+		require.NoError(t, tx.Delete(ctx, existing.ID, 0))
+		return tx.Delete(ctx, existing.ID, 0)
+	})
 	assert.Equal(t, ErrNotFound, err)
+
+	albums, err := repo.FindAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, albums, 1)
+	assert.Equal(t, existing.ID, albums[0].ID)
+
+	require.NoError(t, repo.WithinTx(ctx, func(tx Repository) error {
+		return tx.Create(ctx, &Album{Title: "The Wall", Artist: "Pink Floyd", Price: 2499})
+	}))
+
+	albums, err = repo.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, albums, 2)
+}
+
+func TestAlbumRepository_HistoryAndRevert(t *testing.T) {
+	pool := setupTestDB(t)
+	if pool == nil {
+		return
+	}
+	defer cleanupTestDB(t, pool)
+
+	createTestTable(t, pool)
+	repo := NewRepository(pool)
+	ctx := WithActor(context.Background(), Actor{Name: "alice", Source: SourceREST})
+
+	album := &Album{Title: "Blue Train", Artist: "John Coltrane", Price: 5699}
+	require.NoError(t, repo.Create(ctx, album))
+
+	album.Price = 4999
+	require.NoError(t, repo.Update(WithActor(ctx, Actor{Name: "bob", Source: SourceChat}), album))
+	require.NoError(t, repo.Delete(ctx, album.ID, 0))
+	_, err := repo.Restore(ctx, album.ID)
+	require.NoError(t, err)
+
+	history, err := repo.History(ctx, album.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 4)
+
+	// Newest first
+	assert.Equal(t, ActionRestore, history[0].Action)
+	assert.Equal(t, ActionDelete, history[1].Action)
+	assert.NotNil(t, history[1].After.DeletedAt)
+
+	update := history[2]
+	assert.Equal(t, ActionUpdate, update.Action)
+	assert.Equal(t, Money(5699), update.Before.Price)
+	assert.Equal(t, Money(4999), update.After.Price)
+	assert.Equal(t, "bob", update.Actor)
+	assert.Equal(t, SourceChat, update.Source)
+
+	create := history[3]
+	assert.Nil(t, create.Before)
+	assert.Equal(t, "alice", create.Actor)
+
+	// Reverting to the create restores the original price as a new revision
+	reverted, err := repo.Revert(ctx, album.ID, create.ID, 0)
+	require.NoError(t, err)
+	assert.Equal(t, Money(5699), reverted.Price)
+
+	history, err = repo.History(ctx, album.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 5)
+	assert.Equal(t, ActionRevert, history[0].Action)
+	require.NotNil(t, history[0].RevertedFrom)
+	assert.Equal(t, create.ID, *history[0].RevertedFrom)
+
+	_, err = repo.Revert(ctx, album.ID, create.ID, 1)
+	assert.Equal(t, ErrVersionConflict, err)
+	_, err = repo.Revert(ctx, album.ID+1, create.ID, 0)
+	assert.Equal(t, ErrRevisionNotFound, err)
 }
 
 func TestAlbumRepository_ContextCancellation(t *testing.T) {
@@ -251,7 +830,7 @@ func TestAlbumRepository_ContextCancellation(t *testing.T) {
 	cancel() // Cancel immediately
 
 	// Try to create album with cancelled context
-	album := &Album{Title: "Test", Artist: "Test", Price: 9.99}
+	album := &Album{Title: "Test", Artist: "Test", Price: 999}
 	err := repo.Create(ctx, album)
 	assert.Error(t, err, "Should return error for cancelled context")
 }
@@ -273,7 +852,7 @@ func TestAlbumRepository_ConcurrentCreates(t *testing.T) {
 			album := &Album{
 				Title:  "Test Album",
 				Artist: "Test Artist",
-				Price:  9.99,
+				Price:  999,
 			}
 			err := repo.Create(context.Background(), album)
 			assert.NoError(t, err)
@@ -291,3 +870,103 @@ func TestAlbumRepository_ConcurrentCreates(t *testing.T) {
 	require.NoError(t, err)
 	assert.Len(t, albums, 10)
 }
+
+// recordingDB stands in for the pool, logging the statements the repository
+// sends so the transaction path can be checked without Postgres
+type recordingDB struct {
+	calls []string
+}
+
+func (db *recordingDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	db.calls = append(db.calls, "BEGIN")
+	return &recordingTx{db: db}, nil
+}
+
+func (db *recordingDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	db.calls = append(db.calls, fmt.Sprint(sql, args))
+	return pgconn.NewCommandTag("DELETE 0"), nil
+}
+
+func (db *recordingDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	db.calls = append(db.calls, sql)
+	return noRows{}, nil
+}
+
+func (db *recordingDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return noRow{}
+}
+
+type noRow struct{}
+
+func (noRow) Scan(dest ...any) error { return pgx.ErrNoRows }
+
+// noRows is an empty result set
+type noRows struct {
+	pgx.Rows
+}
+
+func (noRows) Next() bool { return false }
+func (noRows) Close()     {}
+func (noRows) Err() error { return nil }
+
+// recordingTx embeds pgx.Tx so only the methods the repository calls need
+// implementing
+type recordingTx struct {
+	pgx.Tx
+	db *recordingDB
+}
+
+func (tx *recordingTx) Begin(ctx context.Context) (pgx.Tx, error) { return tx.db.Begin(ctx) }
+
+func (tx *recordingTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return tx.db.Exec(ctx, sql, args...)
+}
+
+func (tx *recordingTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return tx.db.Query(ctx, sql, args...)
+}
+
+func (tx *recordingTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return tx.db.QueryRow(ctx, sql, args...)
+}
+
+func (tx *recordingTx) Commit(ctx context.Context) error {
+	tx.db.calls = append(tx.db.calls, "COMMIT")
+	return nil
+}
+
+func (tx *recordingTx) Rollback(ctx context.Context) error {
+	tx.db.calls = append(tx.db.calls, "ROLLBACK")
+	return nil
+}
+
+func TestAlbumRepository_TenantTransaction(t *testing.T) {
+	setTenant := "SELECT set_config('app.tenant_id', $1, true)"
+	ctx := middleware.WithTenant(context.Background(), "acme")
+
+	db := &recordingDB{}
+	_, err := (&repository{db: db}).FindByID(ctx, 1)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, []string{"BEGIN", setTenant + "[acme]", "ROLLBACK"}, db.calls)
+
+	db = &recordingDB{}
+	err = (&repository{db: db}).WithinTx(ctx, func(repo Repository) error { return nil })
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BEGIN", setTenant + "[acme]", "COMMIT", "ROLLBACK"}, db.calls)
+
+	// Retention runs without a tenant and opts into every tenant's rows
+	db = &recordingDB{}
+	_, err = (&repository{db: db}).PurgeDeleted(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.Len(t, db.calls, 6)
+	assert.Equal(t, setTenant+"["+middleware.DefaultTenant+"]", db.calls[1])
+	assert.Equal(t, "SELECT set_config('app.all_tenants', 'on', true)[]", db.calls[2])
+	assert.Contains(t, db.calls[3], "FOR UPDATE")
+	assert.Equal(t, "COMMIT", db.calls[4])
+
+	// Purges lock the album so its last state can be recorded
+	db = &recordingDB{}
+	err = (&repository{db: db}).Purge(ctx, 1)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, []string{"BEGIN", setTenant + "[acme]", "ROLLBACK"}, db.calls)
+}
@@ -0,0 +1,44 @@
+package album
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunRetention purges albums that have been in the trash longer than the
+// configured retention, once at start and then every PurgeInterval, until
+// ctx is cancelled. It does nothing when retention is disabled.
+func RunRetention(ctx context.Context, repo Repository, config Config) {
+	if config.TrashRetention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(config.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		purgeExpired(ctx, repo, config.TrashRetention, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeExpired runs a single retention pass
+func purgeExpired(ctx context.Context, repo Repository, retention time.Duration, now time.Time) {
+	purged, err := repo.PurgeDeleted(ctx, now.Add(-retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Failed to purge deleted albums: %v", err)
+		}
+		return
+	}
+
+	if purged > 0 {
+		log.Printf("Purged %d album(s) deleted more than %s ago", purged, retention)
+	}
+}
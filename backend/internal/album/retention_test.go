@@ -0,0 +1,51 @@
+package album
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// purgeRepo records the cutoff passed to PurgeDeleted
+type purgeRepo struct {
+	Repository
+	before time.Time
+	err    error
+}
+
+func (r *purgeRepo) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	r.before = before
+	return 2, r.err
+}
+
+func TestPurgeExpired(t *testing.T) {
+	repo := &purgeRepo{}
+	now := time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	purgeExpired(context.Background(), repo, 48*time.Hour, now)
+	assert.Equal(t, now.Add(-48*time.Hour), repo.before)
+
+	// Failures are logged, not fatal
+	repo.err = errors.New("connection refused")
+	purgeExpired(context.Background(), repo, time.Hour, now)
+}
+
+func TestRunRetention_StopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		RunRetention(ctx, &purgeRepo{}, Config{TrashRetention: time.Hour, PurgeInterval: time.Hour})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RunRetention did not stop after cancel")
+	}
+}
@@ -0,0 +1,95 @@
+package album
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"web-service-gin/backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Revision actions
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionRevert  = "revert"
+	ActionPurge   = "purge"
+)
+
+// Sources of album changes
+const (
+	SourceREST   = "rest"
+	SourceChat   = "chat"
+	SourceSystem = "system"
+)
+
+// Revision records one change to an album. Before is nil for a create; both
+// snapshots hold the album as the API returned it at the time.
+type Revision struct {
+	ID           int64     `json:"id"`
+	AlbumID      int       `json:"album_id"`
+	Action       string    `json:"action"`
+	Before       *Album    `json:"before"`
+	After        *Album    `json:"after"`
+	Actor        string    `json:"actor"`
+	Source       string    `json:"source"`
+	RevertedFrom *int64    `json:"reverted_from,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// RevertRequest represents a request to revert an album to a revision
+type RevertRequest struct {
+	RevisionID int64 `json:"revision_id" binding:"required"`
+}
+
+// Actor identifies who made a change and through which interface
+type Actor struct {
+	Name   string
+	Source string
+}
+
+type actorKey struct{}
+
+// WithActor returns a context that attributes album changes to the actor
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor changes made with ctx are attributed to.
+// Changes made outside a request, such as by background jobs, belong to
+// the system.
+func ActorFrom(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorKey{}).(Actor); ok {
+		return actor
+	}
+	return Actor{Name: SourceSystem, Source: SourceSystem}
+}
+
+// TrackActor is middleware that attributes album changes made while handling
+// a request to the authenticated caller, or to "anonymous" when
+// authentication is disabled, through the given source
+func TrackActor(source string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor := Actor{Name: "anonymous", Source: source}
+		if principal, ok := middleware.PrincipalFrom(c); ok {
+			actor.Name = principal.Subject
+		}
+		c.Request = c.Request.WithContext(WithActor(c.Request.Context(), actor))
+		c.Next()
+	}
+}
+
+// snapshot renders an album for a revision. Converted prices depend on the
+// request, so they are left out.
+func snapshot(a *Album) ([]byte, error) {
+	if a == nil {
+		return nil, nil
+	}
+	copied := *a
+	copied.Converted = nil
+	return json.Marshal(copied)
+}
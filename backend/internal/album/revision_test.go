@@ -0,0 +1,133 @@
+package album
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"web-service-gin/backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// revisionRepo serves a fixed album and reverts it to a fixed revision
+type revisionRepo struct {
+	Repository
+	album    Album
+	revision Revision
+	reverted int
+}
+
+func (r *revisionRepo) FindByID(ctx context.Context, id int) (*Album, error) {
+	if id != r.album.ID {
+		return nil, ErrNotFound
+	}
+	found := r.album
+	return &found, nil
+}
+
+func (r *revisionRepo) Revert(ctx context.Context, albumID int, revisionID int64, version int) (*Album, error) {
+	if revisionID != r.revision.ID {
+		return nil, ErrRevisionNotFound
+	}
+	if version != r.album.Version {
+		return nil, ErrVersionConflict
+	}
+	r.reverted++
+	r.album.Title, r.album.Price = r.revision.After.Title, r.revision.After.Price
+	r.album.Version++
+	return &r.album, nil
+}
+
+func TestActorFrom(t *testing.T) {
+	assert.Equal(t, Actor{Name: SourceSystem, Source: SourceSystem}, ActorFrom(context.Background()))
+
+	ctx := WithActor(context.Background(), Actor{Name: "alice", Source: SourceChat})
+	assert.Equal(t, Actor{Name: "alice", Source: SourceChat}, ActorFrom(ctx))
+}
+
+func TestTrackActor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(TrackActor(SourceREST))
+
+	var actor Actor
+	router.GET("/", func(c *gin.Context) {
+		actor = ActorFrom(c.Request.Context())
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, Actor{Name: "anonymous", Source: SourceREST}, actor)
+
+	// Authenticated callers are named by their subject
+	router = gin.New()
+	router.Use(func(c *gin.Context) {
+		middleware.SetPrincipal(c, &middleware.Principal{Subject: "alice"})
+	}, TrackActor(SourceChat))
+	router.GET("/", func(c *gin.Context) {
+		actor = ActorFrom(c.Request.Context())
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, Actor{Name: "alice", Source: SourceChat}, actor)
+}
+
+func TestSnapshot(t *testing.T) {
+	data, err := snapshot(&Album{ID: 1, Title: "Blue Train", Price: 5699, Converted: &ConvertedPrice{Currency: "EUR"}})
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "converted_price")
+
+	var decoded Album
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, Money(5699), decoded.Price)
+
+	data, err = snapshot(nil)
+	require.NoError(t, err)
+	assert.Nil(t, data)
+}
+
+func TestHandler_RevertAlbum(t *testing.T) {
+	newRepo := func() *revisionRepo {
+		return &revisionRepo{
+			album:    Album{ID: 1, Title: "Blue Train (Remaster)", Price: 7999, Version: 3},
+			revision: Revision{ID: 10, AlbumID: 1, Action: ActionCreate, After: &Album{ID: 1, Title: "Blue Train", Price: 5699, Version: 1}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		ifMatch string
+		want    int
+	}{
+		{"reverts", `{"revision_id": 10}`, `"3"`, http.StatusOK},
+		{"unknown revision", `{"revision_id": 11}`, "", http.StatusNotFound},
+		{"stale version", `{"revision_id": 10}`, `"2"`, http.StatusPreconditionFailed},
+		{"missing revision id", `{}`, "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo()
+			req := httptest.NewRequest(http.MethodPost, "/albums/1/revert", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			newTestRouter(repo, Config{}).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want, w.Code)
+			if tt.want == http.StatusOK {
+				assert.Equal(t, 1, repo.reverted)
+				assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+				assert.Contains(t, w.Body.String(), `"title":"Blue Train"`)
+			}
+		})
+	}
+}
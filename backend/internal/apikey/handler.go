@@ -0,0 +1,134 @@
+package apikey
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"web-service-gin/backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler handles API key HTTP requests. Every route requires the admin token.
+type Handler struct {
+	repo       Repository
+	adminToken string
+}
+
+// NewHandler creates a new API key handler. An empty admin token disables
+// key management.
+func NewHandler(repo Repository, adminToken string) *Handler {
+	return &Handler{repo: repo, adminToken: adminToken}
+}
+
+// RegisterRoutes registers API key routes
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.Use(middleware.RequireAdminToken(h.adminToken, "Managing API keys requires an admin token"))
+
+	router.GET("", h.ListKeys)
+	router.POST("", h.CreateKey)
+	router.POST("/:id/rotate", h.RotateKey)
+	router.DELETE("/:id", h.RevokeKey)
+}
+
+// ListKeys handles GET /api-keys
+func (h *Handler) ListKeys(c *gin.Context) {
+	keys, err := h.repo.FindAll(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve API keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// CreateKey handles POST /api-keys. The response is the only time the key
+// is shown.
+func (h *Handler) CreateKey(c *gin.Context) {
+	var req CreateKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.validate(time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret, prefix, hash, err := generate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate API key"})
+		return
+	}
+
+	key := APIKey{
+		Name:      req.Name,
+		Prefix:    prefix,
+		Scopes:    req.Scopes,
+		Tenant:    req.Tenant,
+		ExpiresAt: req.ExpiresAt,
+		hash:      hash,
+	}
+	if err := h.repo.Create(c.Request.Context(), &key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, IssuedKey{APIKey: key, Key: secret})
+}
+
+// RotateKey handles POST /api-keys/:id/rotate. The old key stops working
+// immediately; the new one keeps the name, scopes and expiry.
+func (h *Handler) RotateKey(c *gin.Context) {
+	// Validate and parse ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	secret, prefix, hash, err := generate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate API key"})
+		return
+	}
+
+	key, err := h.repo.Rotate(c.Request.Context(), id, prefix, hash)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		case errors.Is(err, ErrRevoked):
+			c.JSON(http.StatusConflict, gin.H{"error": "Revoked API keys cannot be rotated"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate API key"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, IssuedKey{APIKey: *key, Key: secret})
+}
+
+// RevokeKey handles DELETE /api-keys/:id
+func (h *Handler) RevokeKey(c *gin.Context) {
+	// Validate and parse ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	key, err := h.repo.Revoke(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
@@ -0,0 +1,235 @@
+package apikey
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"web-service-gin/backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRepo keeps API keys in memory
+type memoryRepo struct {
+	keys    []*APIKey
+	touched []int
+}
+
+func (r *memoryRepo) Create(ctx context.Context, key *APIKey) error {
+	key.ID = len(r.keys) + 1
+	key.CreatedAt = time.Now()
+	stored := *key
+	r.keys = append(r.keys, &stored)
+	return nil
+}
+
+func (r *memoryRepo) FindAll(ctx context.Context) ([]APIKey, error) {
+	keys := make([]APIKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, *key)
+	}
+	return keys, nil
+}
+
+func (r *memoryRepo) FindByPrefix(ctx context.Context, prefix string) (*APIKey, error) {
+	for _, key := range r.keys {
+		if key.Prefix == prefix {
+			found := *key
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryRepo) Rotate(ctx context.Context, id int, prefix, hash string) (*APIKey, error) {
+	key, err := r.find(id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrRevoked
+	}
+	now := time.Now()
+	key.Prefix, key.hash, key.RotatedAt = prefix, hash, &now
+	rotated := *key
+	return &rotated, nil
+}
+
+func (r *memoryRepo) Revoke(ctx context.Context, id int) (*APIKey, error) {
+	key, err := r.find(id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt == nil {
+		now := time.Now()
+		key.RevokedAt = &now
+	}
+	revoked := *key
+	return &revoked, nil
+}
+
+func (r *memoryRepo) Touch(ctx context.Context, id int, at time.Time) error {
+	r.touched = append(r.touched, id)
+	return nil
+}
+
+func (r *memoryRepo) find(id int) (*APIKey, error) {
+	for _, key := range r.keys {
+		if key.ID == id {
+			return key, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func newTestRouter(repo Repository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewHandler(repo, "secret").RegisterRoutes(router.Group("/api-keys"))
+	return router
+}
+
+func send(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Admin-Token", "secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandler_RequiresAdmin(t *testing.T) {
+	router := newTestRouter(&memoryRepo{})
+
+	for _, token := range []string{"", "wrong"} {
+		req := httptest.NewRequest(http.MethodGet, "/api-keys", nil)
+		if token != "" {
+			req.Header.Set("X-Admin-Token", token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code, "token %q", token)
+	}
+}
+
+func TestHandler_CreateKey(t *testing.T) {
+	repo := &memoryRepo{}
+	router := newTestRouter(repo)
+
+	w := send(router, http.MethodPost, "/api-keys", `{"name": "ingest", "scopes": ["albums:read", "albums:write"]}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var issued IssuedKey
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &issued))
+	assert.Equal(t, "ingest", issued.Name)
+	assert.True(t, strings.HasPrefix(issued.Key, issued.Prefix+"_"))
+	require.Len(t, repo.keys, 1)
+	assert.Equal(t, hashKey(issued.Key), repo.keys[0].hash)
+
+	// The key itself is never listed
+	w = send(router, http.MethodGet, "/api-keys", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), issued.Key)
+	assert.NotContains(t, w.Body.String(), repo.keys[0].hash)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing name", `{"scopes": ["chat"]}`},
+		{"no scopes", `{"name": "ingest", "scopes": []}`},
+		{"unknown scope", `{"name": "ingest", "scopes": ["albums:delete"]}`},
+		{"expired", `{"name": "ingest", "scopes": ["chat"], "expires_at": "2000-01-01T00:00:00Z"}`},
+		{"malformed tenant", `{"name": "ingest", "scopes": ["chat"], "tenant": "Acme Inc"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, http.StatusBadRequest, send(router, http.MethodPost, "/api-keys", tt.body).Code)
+		})
+	}
+}
+
+func TestHandler_RotateAndRevoke(t *testing.T) {
+	repo := &memoryRepo{}
+	router := newTestRouter(repo)
+	verifier := NewVerifier(repo)
+	ctx := context.Background()
+
+	var issued IssuedKey
+	w := send(router, http.MethodPost, "/api-keys", `{"name": "ingest", "scopes": ["chat"]}`)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &issued))
+
+	var rotated IssuedKey
+	w = send(router, http.MethodPost, "/api-keys/1/rotate", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rotated))
+	assert.NotEqual(t, issued.Key, rotated.Key)
+	assert.Equal(t, []string{"chat"}, rotated.Scopes)
+
+	_, err := verifier.VerifyAPIKey(ctx, issued.Key)
+	assert.ErrorIs(t, err, middleware.ErrInvalidAPIKey)
+	_, err = verifier.VerifyAPIKey(ctx, rotated.Key)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, send(router, http.MethodDelete, "/api-keys/1", "").Code)
+	_, err = verifier.VerifyAPIKey(ctx, rotated.Key)
+	assert.ErrorIs(t, err, middleware.ErrAPIKeyRevoked)
+
+	assert.Equal(t, http.StatusConflict, send(router, http.MethodPost, "/api-keys/1/rotate", "").Code)
+	assert.Equal(t, http.StatusNotFound, send(router, http.MethodDelete, "/api-keys/2", "").Code)
+	assert.Equal(t, http.StatusBadRequest, send(router, http.MethodDelete, "/api-keys/abc", "").Code)
+}
+
+func TestVerifier_VerifyAPIKey(t *testing.T) {
+	repo := &memoryRepo{}
+	verifier := NewVerifier(repo)
+	ctx := context.Background()
+
+	key, prefix, hash, err := generate()
+	require.NoError(t, err)
+	expires := time.Now().Add(time.Hour)
+	require.NoError(t, repo.Create(ctx, &APIKey{Name: "ingest", Prefix: prefix, Scopes: []string{"albums:read"}, ExpiresAt: &expires, hash: hash}))
+
+	principal, err := verifier.VerifyAPIKey(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, "api-key:1", principal.Subject)
+	assert.Equal(t, middleware.AuthMethodAPIKey, principal.Method)
+	assert.True(t, principal.HasScope("albums:read"))
+	assert.Equal(t, []int{1}, repo.touched)
+	assert.Empty(t, principal.Tenant)
+
+	// Same prefix, different secret
+	_, err = verifier.VerifyAPIKey(ctx, prefix+"_forged")
+	assert.ErrorIs(t, err, middleware.ErrInvalidAPIKey)
+	_, err = verifier.VerifyAPIKey(ctx, "not-a-key")
+	assert.ErrorIs(t, err, middleware.ErrInvalidAPIKey)
+
+	verifier.now = func() time.Time { return expires.Add(time.Second) }
+	_, err = verifier.VerifyAPIKey(ctx, key)
+	assert.ErrorIs(t, err, middleware.ErrAPIKeyExpired)
+}
+
+func TestVerifier_TenantKey(t *testing.T) {
+	repo := &memoryRepo{}
+	router := newTestRouter(repo)
+
+	var issued IssuedKey
+	w := send(router, http.MethodPost, "/api-keys", `{"name": "acme-ingest", "scopes": ["albums:read"], "tenant": "acme"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &issued))
+	require.NotNil(t, issued.Tenant)
+	assert.Equal(t, "acme", *issued.Tenant)
+
+	principal, err := NewVerifier(repo).VerifyAPIKey(context.Background(), issued.Key)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", principal.Tenant)
+}
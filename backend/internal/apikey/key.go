@@ -0,0 +1,52 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"web-service-gin/backend/internal/middleware"
+)
+
+// generate returns a new API key, its lookup prefix and its hash. Keys have
+// the form alb_<12 hex characters>_<43 base64url characters>; the part up to
+// the second underscore is the prefix, which is stored in the clear to find
+// the key again.
+func generate() (key, prefix, hash string, err error) {
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+
+	prefix = middleware.APIKeyPrefix + hex.EncodeToString(id)
+	key = prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return key, prefix, hashKey(key), nil
+}
+
+// parsePrefix returns the lookup prefix of a key
+func parsePrefix(key string) (string, bool) {
+	if !strings.HasPrefix(key, middleware.APIKeyPrefix) {
+		return "", false
+	}
+	id, secret, ok := strings.Cut(strings.TrimPrefix(key, middleware.APIKeyPrefix), "_")
+	if !ok || len(id) != 12 || secret == "" {
+		return "", false
+	}
+	if _, err := hex.DecodeString(id); err != nil {
+		return "", false
+	}
+	return middleware.APIKeyPrefix + id, true
+}
+
+// hashKey hashes a key for storage. Keys carry 256 random bits, so a single
+// SHA-256 round is enough; a slow password hash would only add latency.
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,41 @@
+package apikey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	key, prefix, hash, err := generate()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(key, prefix+"_"))
+	assert.Len(t, prefix, 16)
+	assert.Equal(t, hashKey(key), hash)
+	assert.True(t, strings.HasPrefix(key, "alb_"))
+
+	parsed, ok := parsePrefix(key)
+	assert.True(t, ok)
+	assert.Equal(t, prefix, parsed)
+
+	other, _, _, err := generate()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+}
+
+func TestParsePrefix_Invalid(t *testing.T) {
+	for _, key := range []string{
+		"",
+		"alb_",
+		"alb_0123456789ab",
+		"alb_0123456789ab_",
+		"alb_0123456789zz_secret",
+		"xyz_0123456789ab_secret",
+	} {
+		_, ok := parsePrefix(key)
+		assert.False(t, ok, key)
+	}
+}
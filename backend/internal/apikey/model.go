@@ -0,0 +1,69 @@
+package apikey
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"web-service-gin/backend/internal/middleware"
+)
+
+// APIKey is a long-lived credential for a machine client. Only a hash of the
+// key is stored; the key itself is shown once, when it is minted or rotated.
+// A nil Tenant binds the key to the default tenant.
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	Tenant     *string    `json:"tenant"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	RotatedAt  *time.Time `json:"rotated_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	hash       string
+}
+
+// IssuedKey is an API key together with its plaintext secret
+type IssuedKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// CreateKeyRequest represents a request to mint an API key. Keys minted
+// without a tenant are limited to the default tenant, as no scope lets a key
+// pick another with X-Tenant-ID.
+type CreateKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=255"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	Tenant    *string    `json:"tenant"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// validate checks the requested scopes and expiry
+func (r CreateKeyRequest) validate(now time.Time) error {
+	for _, scope := range r.Scopes {
+		if !slices.Contains(middleware.Scopes, scope) {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	if r.Tenant != nil && !middleware.ValidTenant(*r.Tenant) {
+		return fmt.Errorf("tenant %q is not a valid tenant ID", *r.Tenant)
+	}
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(now) {
+		return fmt.Errorf("expires_at must be in the future")
+	}
+	return nil
+}
+
+// status reports why the key can no longer be used, if it cannot
+func (k *APIKey) status(now time.Time) error {
+	if k.RevokedAt != nil {
+		return middleware.ErrAPIKeyRevoked
+	}
+	if k.ExpiresAt != nil && !now.Before(*k.ExpiresAt) {
+		return middleware.ErrAPIKeyExpired
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// ErrNotFound is returned when an API key does not exist
+	ErrNotFound = errors.New("API key not found")
+	// ErrRevoked is returned when rotating a key that has been revoked
+	ErrRevoked = errors.New("API key has been revoked")
+)
+
+// Repository handles API key data access
+type Repository interface {
+	Create(ctx context.Context, key *APIKey) error
+	FindAll(ctx context.Context) ([]APIKey, error)
+	FindByPrefix(ctx context.Context, prefix string) (*APIKey, error)
+	Rotate(ctx context.Context, id int, prefix, hash string) (*APIKey, error)
+	Revoke(ctx context.Context, id int) (*APIKey, error)
+	Touch(ctx context.Context, id int, at time.Time) error
+}
+
+// repository implements Repository
+type repository struct {
+	pool *pgxpool.Pool
+}
+
+// NewRepository creates a new API key repository
+func NewRepository(pool *pgxpool.Pool) Repository {
+	return &repository{pool: pool}
+}
+
+const keyColumns = `id, name, prefix, key_hash, scopes, tenant_id, expires_at, last_used_at, revoked_at, rotated_at, created_at`
+
+// Create stores a new API key
+func (r *repository) Create(ctx context.Context, key *APIKey) error {
+	query := `
+		INSERT INTO api_keys (name, prefix, key_hash, scopes, tenant_id, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+
+	return r.pool.QueryRow(ctx, query, key.Name, key.Prefix, key.hash, key.Scopes, key.Tenant, key.ExpiresAt, time.Now()).
+		Scan(&key.ID, &key.CreatedAt)
+}
+
+// FindAll retrieves all API keys, including revoked ones, newest first
+func (r *repository) FindAll(ctx context.Context) ([]APIKey, error) {
+	query := `SELECT ` + keyColumns + ` FROM api_keys ORDER BY created_at DESC, id DESC`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]APIKey, 0)
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// FindByPrefix retrieves the API key with the given lookup prefix
+func (r *repository) FindByPrefix(ctx context.Context, prefix string) (*APIKey, error) {
+	query := `SELECT ` + keyColumns + ` FROM api_keys WHERE prefix = $1`
+
+	key, err := scanKey(r.pool.QueryRow(ctx, query, prefix))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return key, err
+}
+
+// Rotate replaces the secret of an active API key, invalidating the old one
+func (r *repository) Rotate(ctx context.Context, id int, prefix, hash string) (*APIKey, error) {
+	query := `
+		UPDATE api_keys
+		SET prefix = $2, key_hash = $3, rotated_at = $4
+		WHERE id = $1 AND revoked_at IS NULL
+		RETURNING ` + keyColumns
+
+	key, err := scanKey(r.pool.QueryRow(ctx, query, id, prefix, hash, time.Now()))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, r.missingOrRevoked(ctx, id)
+	}
+	return key, err
+}
+
+// Revoke permanently disables an API key. Revoking a revoked key keeps its
+// original revocation time.
+func (r *repository) Revoke(ctx context.Context, id int) (*APIKey, error) {
+	query := `
+		UPDATE api_keys
+		SET revoked_at = COALESCE(revoked_at, $2)
+		WHERE id = $1
+		RETURNING ` + keyColumns
+
+	key, err := scanKey(r.pool.QueryRow(ctx, query, id, time.Now()))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return key, err
+}
+
+// lastUsedResolution is how stale last_used_at may get before a request
+// updates it, so busy clients do not write on every call
+const lastUsedResolution = time.Minute
+
+// Touch records that an API key was used at the given time
+func (r *repository) Touch(ctx context.Context, id int, at time.Time) error {
+	query := `
+		UPDATE api_keys
+		SET last_used_at = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $3)
+	`
+
+	_, err := r.pool.Exec(ctx, query, id, at, at.Add(-lastUsedResolution))
+	return err
+}
+
+// missingOrRevoked explains why an update matched no active key
+func (r *repository) missingOrRevoked(ctx context.Context, id int) error {
+	var exists bool
+	if err := r.pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM api_keys WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrRevoked
+	}
+	return ErrNotFound
+}
+
+func scanKey(row pgx.Row) (*APIKey, error) {
+	var key APIKey
+	err := row.Scan(
+		&key.ID, &key.Name, &key.Prefix, &key.hash, &key.Scopes, &key.Tenant,
+		&key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt, &key.RotatedAt, &key.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
@@ -0,0 +1,65 @@
+package apikey
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"web-service-gin/backend/internal/middleware"
+)
+
+// Verifier authenticates API keys against the repository. It implements
+// middleware.APIKeyVerifier.
+type Verifier struct {
+	repo Repository
+	now  func() time.Time
+}
+
+// NewVerifier creates a new API key verifier
+func NewVerifier(repo Repository) *Verifier {
+	return &Verifier{repo: repo, now: time.Now}
+}
+
+// VerifyAPIKey checks a key and returns a principal named after it and
+// holding its scopes and tenant. Successful checks record when the key was last used.
+func (v *Verifier) VerifyAPIKey(ctx context.Context, key string) (*middleware.Principal, error) {
+	prefix, ok := parsePrefix(key)
+	if !ok {
+		return nil, middleware.ErrInvalidAPIKey
+	}
+
+	stored, err := v.repo.FindByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, middleware.ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashKey(key)), []byte(stored.hash)) != 1 {
+		return nil, middleware.ErrInvalidAPIKey
+	}
+
+	now := v.now()
+	if err := stored.status(now); err != nil {
+		return nil, err
+	}
+
+	// A failed bookkeeping write should not lock the client out
+	if err := v.repo.Touch(ctx, stored.ID, now); err != nil {
+		log.Printf("Failed to record use of API key %d: %v", stored.ID, err)
+	}
+
+	principal := &middleware.Principal{
+		Subject: "api-key:" + strconv.Itoa(stored.ID),
+		Scopes:  stored.Scopes,
+		Method:  middleware.AuthMethodAPIKey,
+	}
+	if stored.Tenant != nil {
+		principal.Tenant = *stored.Tenant
+	}
+	return principal, nil
+}
@@ -0,0 +1,301 @@
+package chat
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"web-service-gin/backend/internal/album"
+	"web-service-gin/backend/internal/middleware"
+)
+
+// Policy controls whether the model may run a tool on its own
+type Policy string
+
+const (
+	// PolicyAuto runs the tool immediately
+	PolicyAuto Policy = "auto"
+	// PolicyConfirm turns the tool call into a pending action the user must confirm
+	PolicyConfirm Policy = "confirm"
+	// PolicyDeny refuses to run the tool
+	PolicyDeny Policy = "deny"
+)
+
+// DefaultActionTTL is how long a pending action can be confirmed
+const DefaultActionTTL = 15 * time.Minute
+
+var (
+	// ErrInvalidActionToken is returned when an action token is malformed or forged
+	ErrInvalidActionToken = errors.New("invalid action token")
+	// ErrActionTenant is returned when an action token is confirmed from another tenant
+	ErrActionTenant = errors.New("action belongs to another tenant")
+	// ErrActionSubject is returned when an action token is confirmed by another user
+	ErrActionSubject = errors.New("action belongs to another user")
+	// ErrActionExpired is returned when an action token is past its expiry
+	ErrActionExpired = errors.New("action token expired")
+	// ErrActionUsed is returned when an action token has already been confirmed
+	ErrActionUsed = errors.New("action already confirmed")
+)
+
+// defaultToolPolicies lists tools that are not PolicyAuto out of the box
+var defaultToolPolicies = map[string]Policy{
+	"update_album": PolicyConfirm,
+	"delete_album": PolicyConfirm,
+}
+
+// ParsePolicy validates a policy name
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(strings.ToLower(strings.TrimSpace(s))); p {
+	case PolicyAuto, PolicyConfirm, PolicyDeny:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown tool policy %q", s)
+	}
+}
+
+// PendingAction is a destructive tool call awaiting user confirmation
+type PendingAction struct {
+	Token      string    `json:"token"`
+	ToolCallID string    `json:"tool_call_id"`
+	Tool       string    `json:"tool"`
+	Arguments  string    `json:"arguments"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// ConfirmActionRequest represents a request to run or reject a pending action
+type ConfirmActionRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ActionResult is the outcome of a confirmed or rejected action
+type ActionResult struct {
+	Tool   string `json:"tool"`
+	Output string `json:"output"`
+}
+
+// actionClaims is the signed payload of an action token
+type actionClaims struct {
+	Nonce     string `json:"n"`
+	Tool      string `json:"t"`
+	Arguments string `json:"a"`
+	Tenant    string `json:"tn"`
+	// Subject is the principal the action was proposed to, empty when
+	// authentication is off
+	Subject string `json:"sb,omitempty"`
+	// ToolCallID and ConversationID identify the proposal, so its outcome
+	// can be recorded in the conversation it was made in, if any
+	ToolCallID     string `json:"c"`
+	ConversationID int    `json:"cv,omitempty"`
+	ExpiresAt      int64  `json:"e"`
+}
+
+// actionSigner issues and verifies action tokens.
+// Tokens are "<payload>.<signature>", both base64url encoded, signed with HMAC-SHA256.
+// Each carries a nonce that ConversationRepository.RedeemAction records, so
+// a token runs only once.
+type actionSigner struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+func newActionSigner(secret []byte, ttl time.Duration) *actionSigner {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			panic(fmt.Sprintf("failed to generate action secret: %v", err))
+		}
+	}
+	if ttl <= 0 {
+		ttl = DefaultActionTTL
+	}
+
+	return &actionSigner{
+		secret: secret,
+		ttl:    ttl,
+	}
+}
+
+// actionOwner is the tenant and principal subject an action token is bound to
+type actionOwner struct {
+	Tenant  string
+	Subject string
+}
+
+// actionOwnerFrom returns the tenant and subject of the caller on ctx. The
+// subject is empty for anonymous callers.
+func actionOwnerFrom(ctx context.Context) actionOwner {
+	owner := actionOwner{Tenant: middleware.TenantFrom(ctx)}
+	if principal, ok := middleware.PrincipalFromContext(ctx); ok {
+		owner.Subject = principal.Subject
+	}
+	return owner
+}
+
+// sign creates a pending action for the given tool call, bound to the caller
+// it was proposed to and the conversation (0 for none) it was proposed in
+func (a *actionSigner) sign(toolCall ToolCall, owner actionOwner, conversationID int, now time.Time) (PendingAction, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return PendingAction{}, err
+	}
+
+	expiresAt := now.Add(a.ttl)
+	payload, err := json.Marshal(actionClaims{
+		Nonce:          hex.EncodeToString(nonce),
+		Tool:           toolCall.Function.Name,
+		Arguments:      toolCall.Function.Arguments,
+		Tenant:         owner.Tenant,
+		Subject:        owner.Subject,
+		ToolCallID:     toolCall.ID,
+		ConversationID: conversationID,
+		ExpiresAt:      expiresAt.Unix(),
+	})
+	if err != nil {
+		return PendingAction{}, err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return PendingAction{
+		Token:      encoded + "." + a.signature(encoded),
+		ToolCallID: toolCall.ID,
+		Tool:       toolCall.Function.Name,
+		Arguments:  toolCall.Function.Arguments,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+// verify checks a token's signature and expiry, and that it is presented by
+// the caller and in the tenant it was issued for
+func (a *actionSigner) verify(token string, owner actionOwner, now time.Time) (*actionClaims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(a.signature(encoded))) {
+		return nil, ErrInvalidActionToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidActionToken
+	}
+
+	var claims actionClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidActionToken
+	}
+
+	expiresAt := time.Unix(claims.ExpiresAt, 0)
+	if now.After(expiresAt) {
+		return nil, ErrActionExpired
+	}
+	if claims.Tenant != owner.Tenant {
+		return nil, ErrActionTenant
+	}
+	if claims.Subject != owner.Subject {
+		return nil, ErrActionSubject
+	}
+
+	return &claims, nil
+}
+
+func (a *actionSigner) signature(encoded string) string {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// policyFor returns the configured policy for a tool
+func (s *Service) policyFor(tool string) Policy {
+	if policy, ok := s.config.ToolPolicies[tool]; ok {
+		return policy
+	}
+	if policy, ok := defaultToolPolicies[tool]; ok {
+		return policy
+	}
+	return PolicyAuto
+}
+
+// rejectedOutput is the tool result recorded for an action the user declined
+const rejectedOutput = `{"status": "rejected", "message": "The user declined this action, so it was not performed."}`
+
+// ConfirmAction runs a pending action after the user has confirmed it. The
+// token is redeemed in the same transaction as the action, so it is only
+// used up if the action runs.
+func (s *Service) ConfirmAction(ctx context.Context, token string) (*ActionResult, error) {
+	return s.settleAction(ctx, token, true)
+}
+
+// RejectAction records that the user declined a pending action, so its
+// token can no longer be confirmed
+func (s *Service) RejectAction(ctx context.Context, token string) (*ActionResult, error) {
+	return s.settleAction(ctx, token, false)
+}
+
+// settleAction redeems an action token and either runs the action or
+// rejects it. The outcome is appended to the conversation the action was
+// proposed in, in the same transaction.
+func (s *Service) settleAction(ctx context.Context, token string, confirmed bool) (*ActionResult, error) {
+	claims, err := s.actions.verify(token, actionOwnerFrom(ctx), time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// The policy may have changed since the action was proposed
+	if confirmed && s.policyFor(claims.Tool) == PolicyDeny {
+		return nil, fmt.Errorf("tool %s is disabled by policy", claims.Tool)
+	}
+
+	output := rejectedOutput
+	err = s.conversations.WithinTx(ctx, func(conversations ConversationRepository, albums album.Repository) error {
+		if err := conversations.RedeemAction(ctx, claims.Nonce, time.Unix(claims.ExpiresAt, 0)); err != nil {
+			return err
+		}
+
+		if confirmed {
+			var err error
+			if output, err = s.withAlbums(albums).ExecuteTool(ctx, claims.Tool, claims.Arguments); err != nil {
+				return err
+			}
+		}
+
+		if claims.ConversationID == 0 {
+			return nil
+		}
+		return conversations.AppendMessages(ctx, claims.ConversationID, outcomeMessages(claims, output))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ActionResult{Tool: claims.Tool, Output: output}, nil
+}
+
+// outcomeMessages records a settled action as a tool call and its result.
+// The conversation already holds the "pending_confirmation" result of the
+// original call, so the outcome gets a call of its own, which keeps the
+// history valid for providers that pair every tool message with a call.
+func outcomeMessages(claims *actionClaims, output string) []StoredMessage {
+	call := ToolCall{
+		ID:       claims.ToolCallID + "_settled",
+		Type:     "function",
+		Function: FunctionCall{Name: claims.Tool, Arguments: claims.Arguments},
+	}
+
+	return []StoredMessage{
+		{Role: RoleAssistant, ToolCalls: []ToolCall{call}},
+		{Role: RoleTool, Content: output, ToolCallID: call.ID},
+	}
+}
+
+// withAlbums returns a copy of the service whose tools use repo
+func (s *Service) withAlbums(repo album.Repository) *Service {
+	bound := *s
+	bound.albumRepo = repo
+	return &bound
+}
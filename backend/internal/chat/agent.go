@@ -0,0 +1,140 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMaxSteps bounds tool rounds per request when none is configured
+	DefaultMaxSteps = 5
+)
+
+// Config holds chat service settings
+type Config struct {
+	// MaxSteps is the maximum number of tool rounds before the model is
+	// asked for a final answer without tools
+	MaxSteps int
+	// ToolPolicies overrides the default policy of individual tools
+	ToolPolicies map[string]Policy
+	// ActionSecret signs pending action tokens. When empty a random secret
+	// is generated, so tokens do not survive a restart.
+	ActionSecret []byte
+	// ActionTTL is how long a pending action can be confirmed
+	ActionTTL time.Duration
+}
+
+// LoadConfig reads chat settings from the environment
+func LoadConfig() (Config, error) {
+	config := Config{MaxSteps: DefaultMaxSteps}
+
+	if raw := os.Getenv("CHAT_MAX_STEPS"); raw != "" {
+		maxSteps, err := strconv.Atoi(raw)
+		if err != nil || maxSteps <= 0 {
+			return config, fmt.Errorf("CHAT_MAX_STEPS must be a positive integer, got %q", raw)
+		}
+		config.MaxSteps = maxSteps
+	}
+
+	// CHAT_TOOL_POLICIES is a comma-separated list of tool=policy pairs
+	if raw := os.Getenv("CHAT_TOOL_POLICIES"); raw != "" {
+		config.ToolPolicies = make(map[string]Policy)
+		for _, pair := range strings.Split(raw, ",") {
+			tool, value, ok := strings.Cut(pair, "=")
+			if !ok {
+				return config, fmt.Errorf("CHAT_TOOL_POLICIES entry %q must be tool=policy", pair)
+			}
+			policy, err := ParsePolicy(value)
+			if err != nil {
+				return config, fmt.Errorf("CHAT_TOOL_POLICIES: %w", err)
+			}
+			config.ToolPolicies[strings.TrimSpace(tool)] = policy
+		}
+	}
+
+	config.ActionSecret = []byte(os.Getenv("CHAT_ACTION_SECRET"))
+
+	if raw := os.Getenv("CHAT_ACTION_TTL"); raw != "" {
+		ttl, err := time.ParseDuration(raw)
+		if err != nil || ttl <= 0 {
+			return config, fmt.Errorf("CHAT_ACTION_TTL must be a positive duration, got %q", raw)
+		}
+		config.ActionTTL = ttl
+	}
+
+	return config, nil
+}
+
+// completeFunc requests the assistant's next message from the provider
+type completeFunc func(ctx context.Context, req CompletionRequest) (CompletionMessage, error)
+
+// runAgent keeps executing tool calls until the model produces a final
+// answer. After MaxSteps tool rounds the model is asked once more without
+// tools so the conversation always ends with a reply.
+//
+// It also returns the messages produced during the run (assistant messages
+// and tool outputs, in order) so callers can persist the transcript.
+func (s *Service) runAgent(ctx context.Context, history []CompletionMessage, complete completeFunc, emit func(StreamEvent)) (*ChatResponse, []CompletionMessage, error) {
+	chatMessages := append([]CompletionMessage{{Role: RoleSystem, Content: systemPrompt}}, history...)
+	start := len(chatMessages)
+	response := &ChatResponse{}
+
+	for step := 0; step < s.config.MaxSteps; step++ {
+		message, err := complete(ctx, CompletionRequest{
+			Messages: chatMessages,
+			Tools:    s.GetToolDefinitions(),
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("chat provider error on step %d: %w", step+1, err)
+		}
+
+		// No tool calls, this is the final answer
+		if len(message.ToolCalls) == 0 {
+			response.Message = message.Content
+			response.Steps = append(response.Steps, Step{Content: message.Content})
+			return response, append(chatMessages[start:], message), nil
+		}
+
+		for _, toolCall := range message.ToolCalls {
+			emit(StreamEvent{Type: EventToolCall, Data: ToolCallEvent{
+				ID:        toolCall.ID,
+				Name:      toolCall.Function.Name,
+				Arguments: toolCall.Function.Arguments,
+			}})
+		}
+
+		toolResults, pending := s.executeToolCalls(ctx, message.ToolCalls)
+		for _, toolResult := range toolResults {
+			emit(StreamEvent{Type: EventToolResult, Data: toolResult})
+		}
+		for _, action := range pending {
+			emit(StreamEvent{Type: EventActionRequired, Data: action})
+		}
+
+		chatMessages = appendToolResults(chatMessages, message, toolResults)
+		response.ToolCalls = append(response.ToolCalls, message.ToolCalls...)
+		response.ToolResults = append(response.ToolResults, toolResults...)
+		response.PendingActions = append(response.PendingActions, pending...)
+		response.Steps = append(response.Steps, Step{
+			Content:     message.Content,
+			ToolCalls:   message.ToolCalls,
+			ToolResults: toolResults,
+		})
+	}
+
+	// Step limit reached, ask for a final answer without offering tools
+	final, err := complete(ctx, CompletionRequest{Messages: chatMessages})
+	if err != nil {
+		return nil, nil, fmt.Errorf("chat provider error on final step: %w", err)
+	}
+
+	response.Message = final.Content
+	response.Steps = append(response.Steps, Step{Content: final.Content})
+	response.StepLimitReached = true
+
+	return response, append(chatMessages[start:], final), nil
+}
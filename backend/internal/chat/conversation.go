@@ -0,0 +1,158 @@
+package chat
+
+import (
+	"context"
+	"time"
+	"unicode/utf8"
+)
+
+// maxTitleLength is the length of titles derived from the first user message
+const maxTitleLength = 60
+
+// Conversation represents a persisted chat conversation
+type Conversation struct {
+	ID        int             `json:"id"`
+	Title     string          `json:"title"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	DeletedAt *time.Time      `json:"deleted_at,omitempty"`
+	Messages  []StoredMessage `json:"messages,omitempty"`
+}
+
+// StoredMessage is a persisted conversation message, including tool calls and tool results
+type StoredMessage struct {
+	ID             int        `json:"id"`
+	ConversationID int        `json:"conversation_id"`
+	Role           string     `json:"role"`
+	Content        string     `json:"content"`
+	ToolCalls      []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID     string     `json:"tool_call_id,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// CreateConversationRequest represents a request to start a conversation
+type CreateConversationRequest struct {
+	Title string `json:"title"`
+}
+
+// ContinueConversationRequest represents a new user message in a conversation
+type ContinueConversationRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// CreateConversation starts a new, empty conversation
+func (s *Service) CreateConversation(ctx context.Context, title string) (*Conversation, error) {
+	conversation := &Conversation{Title: title}
+	if err := s.conversations.Create(ctx, conversation); err != nil {
+		return nil, err
+	}
+
+	return conversation, nil
+}
+
+// ListConversations returns all conversations, most recently active first
+func (s *Service) ListConversations(ctx context.Context) ([]Conversation, error) {
+	return s.conversations.FindAll(ctx)
+}
+
+// GetConversation returns a conversation with its full message history
+func (s *Service) GetConversation(ctx context.Context, id int) (*Conversation, error) {
+	return s.conversations.FindByID(ctx, id)
+}
+
+// DeleteConversation deletes a conversation
+func (s *Service) DeleteConversation(ctx context.Context, id int) error {
+	return s.conversations.Delete(ctx, id)
+}
+
+// ContinueConversation sends a new user message in a stored conversation and
+// persists the user message together with everything the assistant did
+func (s *Service) ContinueConversation(ctx context.Context, id int, content string) (*ChatResponse, error) {
+	return s.continueConversation(ctx, id, content, s.provider.CreateCompletion, func(StreamEvent) {})
+}
+
+// ContinueConversationStream is ContinueConversation with the reply streamed as events
+func (s *Service) ContinueConversationStream(ctx context.Context, id int, content string, emit func(StreamEvent)) error {
+	response, err := s.continueConversation(ctx, id, content, s.streamingComplete(emit), emit)
+	if err != nil {
+		emit(StreamEvent{Type: EventError, Data: ErrorEvent{Error: err.Error()}})
+		return err
+	}
+
+	emit(StreamEvent{Type: EventDone, Data: response})
+	return nil
+}
+
+func (s *Service) continueConversation(ctx context.Context, id int, content string, complete completeFunc, emit func(StreamEvent)) (*ChatResponse, error) {
+	ctx = withConversation(ctx, id)
+	conversation, err := s.conversations.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]CompletionMessage, 0, len(conversation.Messages)+1)
+	for _, stored := range conversation.Messages {
+		history = append(history, CompletionMessage{
+			Role:       stored.Role,
+			Content:    stored.Content,
+			ToolCalls:  stored.ToolCalls,
+			ToolCallID: stored.ToolCallID,
+		})
+	}
+
+	userMessage := CompletionMessage{Role: RoleUser, Content: content}
+	history = append(history, userMessage)
+
+	response, transcript, err := s.runAgent(ctx, history, complete, emit)
+	if err != nil {
+		return nil, err
+	}
+
+	// The user message is only saved alongside a reply, so a failed
+	// request can simply be retried
+	stored := make([]StoredMessage, 0, len(transcript)+1)
+	for _, message := range append([]CompletionMessage{userMessage}, transcript...) {
+		stored = append(stored, StoredMessage{
+			Role:       message.Role,
+			Content:    message.Content,
+			ToolCalls:  message.ToolCalls,
+			ToolCallID: message.ToolCallID,
+		})
+	}
+
+	if err := s.conversations.AppendMessages(ctx, id, stored); err != nil {
+		return nil, err
+	}
+
+	if conversation.Title == "" {
+		if err := s.conversations.SetTitle(ctx, id, titleFrom(content)); err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
+
+type conversationContextKey struct{}
+
+// withConversation marks ctx as running in a stored conversation, so pending
+// actions proposed in it can record their outcome there
+func withConversation(ctx context.Context, id int) context.Context {
+	return context.WithValue(ctx, conversationContextKey{}, id)
+}
+
+// conversationFrom returns the conversation ctx runs in, or 0 for none
+func conversationFrom(ctx context.Context) int {
+	id, _ := ctx.Value(conversationContextKey{}).(int)
+	return id
+}
+
+// titleFrom derives a conversation title from the first user message
+func titleFrom(content string) string {
+	if utf8.RuneCountInString(content) <= maxTitleLength {
+		return content
+	}
+
+	runes := []rune(content)
+	return string(runes[:maxTitleLength-1]) + "…"
+}
@@ -0,0 +1,345 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"web-service-gin/backend/internal/album"
+	"web-service-gin/backend/internal/middleware"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// ErrConversationNotFound is returned when a conversation does not exist
+	ErrConversationNotFound = errors.New("conversation not found")
+)
+
+// ConversationRepository handles conversation data access
+type ConversationRepository interface {
+	Create(ctx context.Context, conversation *Conversation) error
+	FindAll(ctx context.Context) ([]Conversation, error)
+	FindByID(ctx context.Context, id int) (*Conversation, error)
+	AppendMessages(ctx context.Context, conversationID int, messages []StoredMessage) error
+	SetTitle(ctx context.Context, id int, title string) error
+	Delete(ctx context.Context, id int) error
+	RedeemAction(ctx context.Context, nonce string, expiresAt time.Time) error
+	WithinTx(ctx context.Context, fn func(conversations ConversationRepository, albums album.Repository) error) error
+}
+
+// beginner starts transactions; pgxpool.Pool implements it
+type beginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// conversationRepository implements ConversationRepository. Conversations
+// belong to the tenant on the context and to the subject of its principal
+// (see middleware.TenantFrom and middleware.PrincipalFromContext); callers
+// only ever see their own.
+type conversationRepository struct {
+	db beginner
+}
+
+// NewConversationRepository creates a new conversation repository
+func NewConversationRepository(pool *pgxpool.Pool) ConversationRepository {
+	return &conversationRepository{db: pool}
+}
+
+// owner returns the subject conversations on ctx belong to, or nil for
+// anonymous callers
+func owner(ctx context.Context) *string {
+	if principal, ok := middleware.PrincipalFromContext(ctx); ok {
+		return &principal.Subject
+	}
+	return nil
+}
+
+// scoped runs fn in a transaction with app.tenant_id, which the row-level
+// security policies read, set to the tenant on ctx. It commits if fn
+// returns nil.
+func (r *conversationRepository) scoped(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.tenant_id', $1, true)", middleware.TenantFrom(ctx)); err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Create creates a new conversation
+func (r *conversationRepository) Create(ctx context.Context, conversation *Conversation) error {
+	query := `
+		INSERT INTO conversations (title, tenant_id, owner, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`
+
+	now := time.Now()
+	return r.scoped(ctx, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, conversation.Title, middleware.TenantFrom(ctx), owner(ctx), now, now).
+			Scan(&conversation.ID, &conversation.CreatedAt, &conversation.UpdatedAt)
+	})
+}
+
+// FindAll retrieves all conversations without their messages (excluding soft-deleted)
+func (r *conversationRepository) FindAll(ctx context.Context) ([]Conversation, error) {
+	query := `
+		SELECT id, title, created_at, updated_at, deleted_at
+		FROM conversations
+		WHERE tenant_id = $1 AND owner IS NOT DISTINCT FROM $2 AND deleted_at IS NULL
+		ORDER BY updated_at DESC, id DESC
+	`
+
+	conversations := make([]Conversation, 0)
+	err := r.scoped(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, middleware.TenantFrom(ctx), owner(ctx))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var conversation Conversation
+			err := rows.Scan(
+				&conversation.ID,
+				&conversation.Title,
+				&conversation.CreatedAt,
+				&conversation.UpdatedAt,
+				&conversation.DeletedAt,
+			)
+			if err != nil {
+				return err
+			}
+			conversations = append(conversations, conversation)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return conversations, nil
+}
+
+// FindByID retrieves a conversation with its messages in order
+func (r *conversationRepository) FindByID(ctx context.Context, id int) (*Conversation, error) {
+	query := `
+		SELECT id, title, created_at, updated_at, deleted_at
+		FROM conversations
+		WHERE id = $1 AND tenant_id = $2 AND owner IS NOT DISTINCT FROM $3 AND deleted_at IS NULL
+	`
+
+	messagesQuery := `
+		SELECT id, conversation_id, role, content, tool_calls, COALESCE(tool_call_id, ''), created_at
+		FROM conversation_messages
+		WHERE conversation_id = $1
+		ORDER BY id
+	`
+
+	var conversation Conversation
+	err := r.scoped(ctx, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, query, id, middleware.TenantFrom(ctx), owner(ctx)).Scan(
+			&conversation.ID,
+			&conversation.Title,
+			&conversation.CreatedAt,
+			&conversation.UpdatedAt,
+			&conversation.DeletedAt,
+		)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrConversationNotFound
+			}
+			return err
+		}
+
+		rows, err := tx.Query(ctx, messagesQuery, id)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		conversation.Messages = make([]StoredMessage, 0)
+		for rows.Next() {
+			var message StoredMessage
+			var toolCalls []byte
+			err := rows.Scan(
+				&message.ID,
+				&message.ConversationID,
+				&message.Role,
+				&message.Content,
+				&toolCalls,
+				&message.ToolCallID,
+				&message.CreatedAt,
+			)
+			if err != nil {
+				return err
+			}
+			if toolCalls != nil {
+				if err := json.Unmarshal(toolCalls, &message.ToolCalls); err != nil {
+					return err
+				}
+			}
+			conversation.Messages = append(conversation.Messages, message)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &conversation, nil
+}
+
+// AppendMessages adds messages to a conversation in a single transaction
+func (r *conversationRepository) AppendMessages(ctx context.Context, conversationID int, messages []StoredMessage) error {
+	return r.scoped(ctx, func(tx pgx.Tx) error {
+		return appendMessages(ctx, tx, conversationID, messages)
+	})
+}
+
+// appendMessages adds messages to a conversation within tx
+func appendMessages(ctx context.Context, tx pgx.Tx, conversationID int, messages []StoredMessage) error {
+	now := time.Now()
+	result, err := tx.Exec(ctx, `
+		UPDATE conversations
+		SET updated_at = $1
+		WHERE id = $2 AND tenant_id = $3 AND owner IS NOT DISTINCT FROM $4 AND deleted_at IS NULL
+	`, now, conversationID, middleware.TenantFrom(ctx), owner(ctx))
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrConversationNotFound
+	}
+
+	query := `
+		INSERT INTO conversation_messages (conversation_id, role, content, tool_calls, tool_call_id, created_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+		RETURNING id, created_at
+	`
+
+	for i := range messages {
+		message := &messages[i]
+
+		// A nil slice stores SQL NULL rather than a JSON null
+		var toolCalls []byte
+		if len(message.ToolCalls) > 0 {
+			if toolCalls, err = json.Marshal(message.ToolCalls); err != nil {
+				return err
+			}
+		}
+
+		message.ConversationID = conversationID
+		err := tx.QueryRow(
+			ctx,
+			query,
+			conversationID,
+			message.Role,
+			message.Content,
+			toolCalls,
+			message.ToolCallID,
+			now,
+		).Scan(&message.ID, &message.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetTitle renames a conversation
+func (r *conversationRepository) SetTitle(ctx context.Context, id int, title string) error {
+	query := `
+		UPDATE conversations
+		SET title = $1
+		WHERE id = $2 AND tenant_id = $3 AND owner IS NOT DISTINCT FROM $4 AND deleted_at IS NULL
+	`
+
+	return r.scoped(ctx, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, query, title, id, middleware.TenantFrom(ctx), owner(ctx))
+		if err != nil {
+			return err
+		}
+
+		if result.RowsAffected() == 0 {
+			return ErrConversationNotFound
+		}
+
+		return nil
+	})
+}
+
+// Delete deletes a conversation by ID (soft delete, messages are kept for auditing)
+func (r *conversationRepository) Delete(ctx context.Context, id int) error {
+	query := `
+		UPDATE conversations
+		SET deleted_at = $1
+		WHERE id = $2 AND tenant_id = $3 AND owner IS NOT DISTINCT FROM $4 AND deleted_at IS NULL
+	`
+
+	return r.scoped(ctx, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, query, time.Now(), id, middleware.TenantFrom(ctx), owner(ctx))
+		if err != nil {
+			return err
+		}
+
+		if result.RowsAffected() == 0 {
+			return ErrConversationNotFound
+		}
+
+		return nil
+	})
+}
+
+// RedeemAction records that the action token with the given nonce has been
+// confirmed, returning ErrActionUsed if it already was. Redemptions are kept
+// until the token expires.
+func (r *conversationRepository) RedeemAction(ctx context.Context, nonce string, expiresAt time.Time) error {
+	return r.scoped(ctx, func(tx pgx.Tx) error {
+		now := time.Now()
+		if _, err := tx.Exec(ctx, `DELETE FROM chat_action_redemptions WHERE expires_at < $1`, now); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(ctx, `
+			INSERT INTO chat_action_redemptions (nonce, expires_at, redeemed_at)
+			VALUES ($1, $2, $3)
+		`, nonce, expiresAt, now)
+
+		// A concurrent redemption of the same nonce waits for this one and
+		// then fails the primary key
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return ErrActionUsed
+		}
+		return err
+	})
+}
+
+// uniqueViolation is the Postgres error code for a duplicate key
+const uniqueViolation = "23505"
+
+// WithinTx runs fn with conversation and album repositories bound to one
+// transaction scoped to the tenant on ctx. The transaction commits if fn
+// returns nil and rolls back otherwise.
+func (r *conversationRepository) WithinTx(ctx context.Context, fn func(conversations ConversationRepository, albums album.Repository) error) error {
+	return r.scoped(ctx, func(tx pgx.Tx) error {
+		return fn(&conversationRepository{db: tx}, album.NewTxRepository(tx))
+	})
+}
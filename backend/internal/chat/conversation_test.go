@@ -0,0 +1,189 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"web-service-gin/backend/internal/album"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryConversations is an in-memory ConversationRepository for offline
+// tests. Its transactions hand fn the albums repository and never roll back.
+type memoryConversations struct {
+	nextID        int
+	conversations map[int]*Conversation
+	redeemed      map[string]bool
+	albums        album.Repository
+}
+
+func newMemoryConversations(albums album.Repository) *memoryConversations {
+	return &memoryConversations{
+		conversations: make(map[int]*Conversation),
+		redeemed:      make(map[string]bool),
+		albums:        albums,
+	}
+}
+
+func (r *memoryConversations) Create(ctx context.Context, conversation *Conversation) error {
+	r.nextID++
+	conversation.ID = r.nextID
+	stored := *conversation
+	r.conversations[conversation.ID] = &stored
+	return nil
+}
+
+func (r *memoryConversations) FindAll(ctx context.Context) ([]Conversation, error) {
+	conversations := make([]Conversation, 0, len(r.conversations))
+	for _, conversation := range r.conversations {
+		conversations = append(conversations, *conversation)
+	}
+	return conversations, nil
+}
+
+func (r *memoryConversations) FindByID(ctx context.Context, id int) (*Conversation, error) {
+	conversation, ok := r.conversations[id]
+	if !ok {
+		return nil, ErrConversationNotFound
+	}
+	found := *conversation
+	found.Messages = append([]StoredMessage(nil), conversation.Messages...)
+	return &found, nil
+}
+
+func (r *memoryConversations) AppendMessages(ctx context.Context, conversationID int, messages []StoredMessage) error {
+	conversation, ok := r.conversations[conversationID]
+	if !ok {
+		return ErrConversationNotFound
+	}
+	conversation.Messages = append(conversation.Messages, messages...)
+	return nil
+}
+
+func (r *memoryConversations) SetTitle(ctx context.Context, id int, title string) error {
+	conversation, ok := r.conversations[id]
+	if !ok {
+		return ErrConversationNotFound
+	}
+	conversation.Title = title
+	return nil
+}
+
+func (r *memoryConversations) Delete(ctx context.Context, id int) error {
+	if _, ok := r.conversations[id]; !ok {
+		return ErrConversationNotFound
+	}
+	delete(r.conversations, id)
+	return nil
+}
+
+func (r *memoryConversations) RedeemAction(ctx context.Context, nonce string, expiresAt time.Time) error {
+	if r.redeemed[nonce] {
+		return ErrActionUsed
+	}
+	r.redeemed[nonce] = true
+	return nil
+}
+
+func (r *memoryConversations) WithinTx(ctx context.Context, fn func(conversations ConversationRepository, albums album.Repository) error) error {
+	return fn(r, r.albums)
+}
+
+func TestService_ContinueConversation(t *testing.T) {
+	service := newTestService([]FakeStep{
+		{ToolCalls: []FakeToolCall{{Name: "get_album_by_id", Arguments: json.RawMessage(`{"id": 1}`)}}},
+		{Content: "Album 1 is Blue Train."},
+	})
+	ctx := context.Background()
+
+	conversation, err := service.CreateConversation(ctx, "")
+	require.NoError(t, err)
+
+	response, err := service.ContinueConversation(ctx, conversation.ID, "What is album 1?")
+	require.NoError(t, err)
+	assert.Equal(t, "Album 1 is Blue Train.", response.Message)
+
+	// User message, tool-call message, tool result and final reply are stored in order
+	stored, err := service.GetConversation(ctx, conversation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "What is album 1?", stored.Title)
+	require.Len(t, stored.Messages, 4)
+	assert.Equal(t, RoleUser, stored.Messages[0].Role)
+	assert.Equal(t, RoleAssistant, stored.Messages[1].Role)
+	require.Len(t, stored.Messages[1].ToolCalls, 1)
+	assert.Equal(t, RoleTool, stored.Messages[2].Role)
+	assert.Equal(t, stored.Messages[1].ToolCalls[0].ID, stored.Messages[2].ToolCallID)
+	assert.Contains(t, stored.Messages[2].Content, "Blue Train")
+	assert.Equal(t, "Album 1 is Blue Train.", stored.Messages[3].Content)
+
+	// The next turn replays the stored history to the provider
+	response, err = service.ContinueConversation(ctx, conversation.ID, "thanks")
+	require.NoError(t, err)
+	assert.Len(t, response.ToolCalls, 1)
+
+	stored, err = service.GetConversation(ctx, conversation.ID)
+	require.NoError(t, err)
+	assert.Len(t, stored.Messages, 8)
+	assert.Equal(t, "What is album 1?", stored.Title)
+}
+
+func TestService_ConversationRecordsActionOutcome(t *testing.T) {
+	service := newTestService([]FakeStep{
+		{ToolCalls: []FakeToolCall{
+			{Name: "delete_album", Arguments: json.RawMessage(`{"id": 3}`)},
+			{Name: "delete_album", Arguments: json.RawMessage(`{"id": 2}`)},
+		}},
+		{Content: "Please confirm."},
+	})
+	ctx := context.Background()
+
+	conversation, err := service.CreateConversation(ctx, "")
+	require.NoError(t, err)
+
+	response, err := service.ContinueConversation(ctx, conversation.ID, "Delete albums 3 and 2")
+	require.NoError(t, err)
+	require.Len(t, response.PendingActions, 2)
+
+	confirmed, err := service.ConfirmAction(ctx, response.PendingActions[0].Token)
+	require.NoError(t, err)
+	rejected, err := service.RejectAction(ctx, response.PendingActions[1].Token)
+	require.NoError(t, err)
+	assert.Contains(t, rejected.Output, "rejected")
+	assert.Len(t, service.albumRepo.(*memoryRepo).albums, 2)
+
+	// A rejected action cannot be confirmed afterwards
+	_, err = service.ConfirmAction(ctx, response.PendingActions[1].Token)
+	assert.ErrorIs(t, err, ErrActionUsed)
+
+	// Each outcome follows the proposal as a tool call and its result
+	stored, err := service.GetConversation(ctx, conversation.ID)
+	require.NoError(t, err)
+	require.Len(t, stored.Messages, 9)
+	outcomes := stored.Messages[5:]
+	assert.Equal(t, RoleAssistant, outcomes[0].Role)
+	require.Len(t, outcomes[0].ToolCalls, 1)
+	assert.Equal(t, "delete_album", outcomes[0].ToolCalls[0].Function.Name)
+	assert.Equal(t, outcomes[0].ToolCalls[0].ID, outcomes[1].ToolCallID)
+	assert.Equal(t, confirmed.Output, outcomes[1].Content)
+	assert.Equal(t, RoleTool, outcomes[3].Role)
+	assert.Equal(t, rejectedOutput, outcomes[3].Content)
+}
+
+func TestService_ContinueConversation_NotFound(t *testing.T) {
+	service := newTestService(nil)
+
+	_, err := service.ContinueConversation(context.Background(), 42, "hello")
+	assert.ErrorIs(t, err, ErrConversationNotFound)
+}
+
+func TestTitleFrom(t *testing.T) {
+	assert.Equal(t, "short", titleFrom("short"))
+
+	long := titleFrom("Find every album by John Coltrane and raise the price of each one by ten percent")
+	assert.Equal(t, maxTitleLength, len([]rune(long)))
+	assert.Equal(t, "…", string([]rune(long)[maxTitleLength-1:]))
+}
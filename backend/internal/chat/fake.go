@@ -0,0 +1,120 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// FakeStep is one scripted assistant turn
+type FakeStep struct {
+	Content   string         `json:"content"`
+	ToolCalls []FakeToolCall `json:"tool_calls,omitempty"`
+}
+
+// FakeToolCall is a scripted tool call with its arguments as a JSON object
+type FakeToolCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// FakeProvider is a deterministic offline Provider.
+//
+// Each user message replays the script from the beginning: the step used is
+// the number of assistant messages since the last user message. Once the
+// script is exhausted (or when there is no script) the provider echoes the
+// last user message, followed by any tool outputs it has seen. Scripted tool
+// calls are never made when the request offers no tools.
+type FakeProvider struct {
+	steps []FakeStep
+}
+
+// NewFakeProvider creates a fake provider that plays back the given steps
+func NewFakeProvider(steps []FakeStep) *FakeProvider {
+	return &FakeProvider{steps: steps}
+}
+
+// LoadFakeProvider creates a fake provider from a JSON file holding an array of steps
+func LoadFakeProvider(path string) (*FakeProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fake chat script: %w", err)
+	}
+
+	var steps []FakeStep
+	if err := json.Unmarshal(data, &steps); err != nil {
+		return nil, fmt.Errorf("failed to parse fake chat script: %w", err)
+	}
+
+	return NewFakeProvider(steps), nil
+}
+
+// CreateCompletion returns the next scripted message
+func (p *FakeProvider) CreateCompletion(ctx context.Context, req CompletionRequest) (CompletionMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return CompletionMessage{}, err
+	}
+
+	return p.next(req), nil
+}
+
+// StreamCompletion returns the next scripted message, emitting its content word by word
+func (p *FakeProvider) StreamCompletion(ctx context.Context, req CompletionRequest, onDelta func(string)) (CompletionMessage, error) {
+	message, err := p.CreateCompletion(ctx, req)
+	if err != nil {
+		return CompletionMessage{}, err
+	}
+
+	for _, word := range strings.SplitAfter(message.Content, " ") {
+		if word != "" {
+			onDelta(word)
+		}
+	}
+
+	return message, nil
+}
+
+// next picks the scripted step for the current position in the conversation
+func (p *FakeProvider) next(req CompletionRequest) CompletionMessage {
+	step := 0
+	lastUser := ""
+	var toolOutputs []string
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case RoleUser:
+			step = 0
+			lastUser = msg.Content
+			toolOutputs = nil
+		case RoleAssistant:
+			step++
+		case RoleTool:
+			toolOutputs = append(toolOutputs, msg.Content)
+		}
+	}
+
+	if step < len(p.steps) && (len(req.Tools) > 0 || len(p.steps[step].ToolCalls) == 0) {
+		scripted := p.steps[step]
+		message := CompletionMessage{Role: RoleAssistant, Content: scripted.Content}
+		for i, call := range scripted.ToolCalls {
+			arguments := string(call.Arguments)
+			if arguments == "" {
+				arguments = "{}"
+			}
+			message.ToolCalls = append(message.ToolCalls, ToolCall{
+				ID:       fmt.Sprintf("call_%d_%d", step, i),
+				Type:     "function",
+				Function: FunctionCall{Name: call.Name, Arguments: arguments},
+			})
+		}
+		return message
+	}
+
+	content := "You said: " + lastUser
+	if len(toolOutputs) > 0 {
+		content += "\nTool results: " + strings.Join(toolOutputs, "\n")
+	}
+
+	return CompletionMessage{Role: RoleAssistant, Content: content}
+}
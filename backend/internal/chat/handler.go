@@ -1,7 +1,12 @@
 package chat
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
+
+	"web-service-gin/backend/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,7 +23,19 @@ func NewHandler(service *Service) *Handler {
 
 // RegisterRoutes registers chat routes
 func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.Use(middleware.RequirePermission(middleware.PermChat))
+
 	router.POST("", h.Chat)
+	router.POST("/stream", h.ChatStream)
+	router.POST("/actions/confirm", h.ConfirmAction)
+	router.POST("/actions/reject", h.RejectAction)
+
+	router.GET("/conversations", h.ListConversations)
+	router.POST("/conversations", h.CreateConversation)
+	router.GET("/conversations/:id", h.GetConversation)
+	router.POST("/conversations/:id/messages", h.ContinueConversation)
+	router.POST("/conversations/:id/messages/stream", h.ContinueConversationStream)
+	router.DELETE("/conversations/:id", h.DeleteConversation)
 }
 
 // Chat handles chat requests
@@ -38,3 +55,202 @@ func (h *Handler) Chat(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// ChatStream handles chat requests, streaming the reply as Server-Sent Events
+func (h *Handler) ChatStream(c *gin.Context) {
+	var req ChatRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	// Errors are delivered to the client as an error event
+	_ = h.service.ChatStream(c.Request.Context(), req.Messages, func(event StreamEvent) {
+		c.SSEvent(event.Type, event.Data)
+		c.Writer.Flush()
+	})
+}
+
+// ConfirmAction runs a destructive tool call the user has confirmed
+func (h *Handler) ConfirmAction(c *gin.Context) {
+	h.settleAction(c, h.service.ConfirmAction)
+}
+
+// RejectAction records that the user declined a destructive tool call
+func (h *Handler) RejectAction(c *gin.Context) {
+	h.settleAction(c, h.service.RejectAction)
+}
+
+func (h *Handler) settleAction(c *gin.Context, settle func(ctx context.Context, token string) (*ActionResult, error)) {
+	var req ConfirmActionRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := settle(c.Request.Context(), req.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidActionToken):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrActionTenant), errors.Is(err, ErrActionSubject):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrActionExpired):
+			c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrActionUsed):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrConversationNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ListConversations retrieves all conversations
+func (h *Handler) ListConversations(c *gin.Context) {
+	conversations, err := h.service.ListConversations(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve conversations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, conversations)
+}
+
+// CreateConversation starts a new conversation
+func (h *Handler) CreateConversation(c *gin.Context) {
+	var req CreateConversationRequest
+
+	// The body is optional
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	conversation, err := h.service.CreateConversation(c.Request.Context(), req.Title)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create conversation"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, conversation)
+}
+
+// GetConversation retrieves a conversation with its messages
+func (h *Handler) GetConversation(c *gin.Context) {
+	// Validate and parse ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+
+	conversation, err := h.service.GetConversation(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, ErrConversationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve conversation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+// ContinueConversation sends a new user message in a conversation
+func (h *Handler) ContinueConversation(c *gin.Context) {
+	// Validate and parse ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+
+	var req ContinueConversationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.service.ContinueConversation(c.Request.Context(), id, req.Content)
+	if err != nil {
+		if errors.Is(err, ErrConversationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ContinueConversationStream sends a new user message in a conversation, streaming the reply
+func (h *Handler) ContinueConversationStream(c *gin.Context) {
+	// Validate and parse ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+
+	var req ContinueConversationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Check the conversation exists before committing to a stream
+	if _, err := h.service.GetConversation(c.Request.Context(), id); err != nil {
+		if errors.Is(err, ErrConversationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve conversation"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	// Errors are delivered to the client as an error event
+	_ = h.service.ContinueConversationStream(c.Request.Context(), id, req.Content, func(event StreamEvent) {
+		c.SSEvent(event.Type, event.Data)
+		c.Writer.Flush()
+	})
+}
+
+// DeleteConversation deletes a conversation by ID
+func (h *Handler) DeleteConversation(c *gin.Context) {
+	// Validate and parse ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+
+	if err := h.service.DeleteConversation(c.Request.Context(), id); err != nil {
+		if errors.Is(err, ErrConversationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete conversation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Conversation deleted successfully"})
+}
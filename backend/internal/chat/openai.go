@@ -0,0 +1,175 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// openaiProvider implements Provider using the OpenAI chat completions API
+type openaiProvider struct {
+	client *openai.Client
+	model  string
+}
+
+// NewOpenAIProvider creates a provider backed by OpenAI.
+// An empty model selects gpt-4o-mini.
+func NewOpenAIProvider(apiKey, model string) Provider {
+	if model == "" {
+		model = openai.GPT4oMini
+	}
+
+	return &openaiProvider{
+		client: openai.NewClient(apiKey),
+		model:  model,
+	}
+}
+
+// CreateCompletion returns the assistant's next message
+func (p *openaiProvider) CreateCompletion(ctx context.Context, req CompletionRequest) (CompletionMessage, error) {
+	resp, err := p.client.CreateChatCompletion(ctx, p.buildRequest(req))
+	if err != nil {
+		return CompletionMessage{}, err
+	}
+
+	if len(resp.Choices) == 0 {
+		return CompletionMessage{}, errors.New("no choices returned")
+	}
+
+	return fromOpenAIMessage(resp.Choices[0].Message), nil
+}
+
+// StreamCompletion streams the assistant's next message, reassembling tool
+// calls from their fragments
+func (p *openaiProvider) StreamCompletion(ctx context.Context, req CompletionRequest, onDelta func(string)) (CompletionMessage, error) {
+	request := p.buildRequest(req)
+	request.Stream = true
+
+	stream, err := p.client.CreateChatCompletionStream(ctx, request)
+	if err != nil {
+		return CompletionMessage{}, err
+	}
+	defer stream.Close()
+
+	var content strings.Builder
+	toolCalls := make(map[int]*ToolCall)
+
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return CompletionMessage{}, err
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		delta := chunk.Choices[0].Delta
+		if delta.Content != "" {
+			content.WriteString(delta.Content)
+			onDelta(delta.Content)
+		}
+
+		// Tool calls arrive in fragments keyed by index
+		for _, fragment := range delta.ToolCalls {
+			index := 0
+			if fragment.Index != nil {
+				index = *fragment.Index
+			}
+
+			call, ok := toolCalls[index]
+			if !ok {
+				call = &ToolCall{Type: string(openai.ToolTypeFunction)}
+				toolCalls[index] = call
+			}
+			if fragment.ID != "" {
+				call.ID = fragment.ID
+			}
+			call.Function.Name += fragment.Function.Name
+			call.Function.Arguments += fragment.Function.Arguments
+		}
+	}
+
+	message := CompletionMessage{
+		Role:    RoleAssistant,
+		Content: content.String(),
+	}
+
+	indexes := make([]int, 0, len(toolCalls))
+	for index := range toolCalls {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		message.ToolCalls = append(message.ToolCalls, *toolCalls[index])
+	}
+
+	return message, nil
+}
+
+// buildRequest converts a provider-neutral request to OpenAI format
+func (p *openaiProvider) buildRequest(req CompletionRequest) openai.ChatCompletionRequest {
+	request := openai.ChatCompletionRequest{
+		Model:    p.model,
+		Messages: make([]openai.ChatCompletionMessage, 0, len(req.Messages)),
+	}
+
+	for _, msg := range req.Messages {
+		message := openai.ChatCompletionMessage{
+			Role:       msg.Role,
+			Content:    msg.Content,
+			ToolCallID: msg.ToolCallID,
+		}
+		for _, call := range msg.ToolCalls {
+			message.ToolCalls = append(message.ToolCalls, openai.ToolCall{
+				ID:   call.ID,
+				Type: openai.ToolTypeFunction,
+				Function: openai.FunctionCall{
+					Name:      call.Function.Name,
+					Arguments: call.Function.Arguments,
+				},
+			})
+		}
+		request.Messages = append(request.Messages, message)
+	}
+
+	for _, tool := range req.Tools {
+		request.Tools = append(request.Tools, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+
+	return request
+}
+
+// fromOpenAIMessage converts an OpenAI response message to provider-neutral form
+func fromOpenAIMessage(msg openai.ChatCompletionMessage) CompletionMessage {
+	message := CompletionMessage{
+		Role:    msg.Role,
+		Content: msg.Content,
+	}
+
+	for _, call := range msg.ToolCalls {
+		message.ToolCalls = append(message.ToolCalls, ToolCall{
+			ID:   call.ID,
+			Type: string(call.Type),
+			Function: FunctionCall{
+				Name:      call.Function.Name,
+				Arguments: call.Function.Arguments,
+			},
+		})
+	}
+
+	return message
+}
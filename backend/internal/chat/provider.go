@@ -0,0 +1,96 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Message roles understood by every provider
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+var (
+	// ErrProviderNotConfigured is returned when no LLM provider is configured
+	ErrProviderNotConfigured = errors.New("chat provider not configured")
+)
+
+// Provider abstracts an LLM backend capable of chat completion with tool calls
+type Provider interface {
+	// CreateCompletion returns the assistant's next message
+	CreateCompletion(ctx context.Context, req CompletionRequest) (CompletionMessage, error)
+	// StreamCompletion returns the assistant's next message, passing content
+	// fragments to onDelta as they are generated
+	StreamCompletion(ctx context.Context, req CompletionRequest, onDelta func(string)) (CompletionMessage, error)
+}
+
+// CompletionRequest is a provider-neutral completion request
+type CompletionRequest struct {
+	Messages []CompletionMessage
+	Tools    []Tool
+}
+
+// CompletionMessage is a single message in a provider conversation
+type CompletionMessage struct {
+	Role       string
+	Content    string
+	ToolCalls  []ToolCall
+	ToolCallID string
+}
+
+// Tool describes a function the model may call
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  json.RawMessage
+}
+
+// ToolCall is a request from the model to run a tool
+type ToolCall struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall names the tool to run and its JSON-encoded arguments
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// NewProviderFromEnv selects a provider from the environment.
+//
+// CHAT_PROVIDER may be "openai" or "fake". When unset, OpenAI is used if
+// OPENAI_API_KEY is present; otherwise ErrProviderNotConfigured is returned
+// so the caller can run without chat.
+func NewProviderFromEnv() (Provider, error) {
+	name := os.Getenv("CHAT_PROVIDER")
+	if name == "" && os.Getenv("OPENAI_API_KEY") != "" {
+		name = "openai"
+	}
+
+	switch name {
+	case "openai":
+		apiKey := os.Getenv("OPENAI_API_KEY")
+		if apiKey == "" {
+			return nil, errors.New("OPENAI_API_KEY environment variable is required for the openai provider")
+		}
+		return NewOpenAIProvider(apiKey, os.Getenv("OPENAI_MODEL")), nil
+	case "fake":
+		path := os.Getenv("CHAT_FAKE_SCRIPT")
+		if path == "" {
+			return NewFakeProvider(nil), nil
+		}
+		return LoadFakeProvider(path)
+	case "", "none":
+		return nil, ErrProviderNotConfigured
+	default:
+		return nil, fmt.Errorf("unknown chat provider: %s", name)
+	}
+}
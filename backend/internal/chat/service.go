@@ -5,31 +5,40 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
+	"time"
 
 	"web-service-gin/backend/internal/album"
+	"web-service-gin/backend/internal/middleware"
+)
 
-	openai "github.com/sashabaranov/go-openai"
+const (
+	// defaultToolLimit caps get_albums results when the model gives no limit
+	defaultToolLimit = 20
+	// maxToolLimit keeps get_albums results small enough for the model context
+	maxToolLimit = 50
 )
 
-// Service handles chat operations with OpenAI
+// Service handles chat operations with an LLM provider
 type Service struct {
-	client    *openai.Client
-	albumRepo album.Repository
+	provider      Provider
+	albumRepo     album.Repository
+	conversations ConversationRepository
+	config        Config
+	actions       *actionSigner
 }
 
 // NewService creates a new chat service
-func NewService(albumRepo album.Repository) *Service {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		panic("OPENAI_API_KEY environment variable is required")
+func NewService(provider Provider, albumRepo album.Repository, conversations ConversationRepository, config Config) *Service {
+	if config.MaxSteps <= 0 {
+		config.MaxSteps = DefaultMaxSteps
 	}
 
-	client := openai.NewClient(apiKey)
-
 	return &Service{
-		client:    client,
-		albumRepo: albumRepo,
+		provider:      provider,
+		albumRepo:     albumRepo,
+		conversations: conversations,
+		config:        config,
+		actions:       newActionSigner(config.ActionSecret, config.ActionTTL),
 	}
 }
 
@@ -44,11 +53,22 @@ type ChatRequest struct {
 	Messages []Message `json:"messages" binding:"required"`
 }
 
-// ChatResponse represents the chat response
+// ChatResponse represents the chat response.
+// ToolCalls and ToolResults aggregate every step; Steps keeps the full trace.
 type ChatResponse struct {
-	Message     string              `json:"message"`
-	ToolCalls   []openai.ToolCall   `json:"tool_calls,omitempty"`
-	ToolResults []ToolResult        `json:"tool_results,omitempty"`
+	Message          string          `json:"message"`
+	ToolCalls        []ToolCall      `json:"tool_calls,omitempty"`
+	ToolResults      []ToolResult    `json:"tool_results,omitempty"`
+	Steps            []Step          `json:"steps,omitempty"`
+	StepLimitReached bool            `json:"step_limit_reached,omitempty"`
+	PendingActions   []PendingAction `json:"pending_actions,omitempty"`
+}
+
+// Step records one model round-trip of the agent loop
+type Step struct {
+	Content     string       `json:"content,omitempty"`
+	ToolCalls   []ToolCall   `json:"tool_calls,omitempty"`
+	ToolResults []ToolResult `json:"tool_results,omitempty"`
 }
 
 // ToolResult represents the result of a tool execution
@@ -57,112 +77,121 @@ type ToolResult struct {
 	Output     string `json:"output"`
 }
 
-// GetToolDefinitions returns the tool definitions for OpenAI
-func (s *Service) GetToolDefinitions() []openai.Tool {
-	return []openai.Tool{
+// GetToolDefinitions returns the tool definitions offered to the model
+func (s *Service) GetToolDefinitions() []Tool {
+	return []Tool{
 		{
-			Type: openai.ToolTypeFunction,
-			Function: &openai.FunctionDefinition{
-				Name:        "get_albums",
-				Description: "Get all albums, optionally filtered by search term",
-				Parameters: json.RawMessage(`{
-					"type": "object",
-					"properties": {
-						"search": {
-							"type": "string",
-							"description": "Optional search term to filter albums by title, artist"
-						}
+			Name:        "get_albums",
+			Description: "Search albums by title or artist and price range. Returns at most 'limit' albums plus the total number of matches",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"search": {
+						"type": "string",
+						"description": "Optional search term to filter albums by title, artist"
+					},
+					"min_price": {
+						"type": "number",
+						"description": "Optional minimum price (inclusive)"
+					},
+					"max_price": {
+						"type": "number",
+						"description": "Optional maximum price (inclusive)"
+					},
+					"currency": {
+						"type": "string",
+						"description": "Three-letter currency code of min_price and max_price (default USD). Albums priced in other currencies are compared after conversion"
+					},
+					"limit": {
+						"type": "number",
+						"description": "Maximum number of albums to return (default 20, max 50)"
 					}
-				}`),
-			},
+				}
+			}`),
 		},
 		{
-			Type: openai.ToolTypeFunction,
-			Function: &openai.FunctionDefinition{
-				Name:        "get_album_by_id",
-				Description: "Get a specific album by its ID",
-				Parameters: json.RawMessage(`{
-					"type": "object",
-					"properties": {
-						"id": {
-							"type": "number",
-							"description": "The ID of the album to retrieve"
-						}
-					},
-					"required": ["id"]
-				}`),
-			},
+			Name:        "get_album_by_id",
+			Description: "Get a specific album by its ID",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"id": {
+						"type": "number",
+						"description": "The ID of the album to retrieve"
+					}
+				},
+				"required": ["id"]
+			}`),
 		},
 		{
-			Type: openai.ToolTypeFunction,
-			Function: &openai.FunctionDefinition{
-				Name:        "create_album",
-				Description: "Create a new album",
-				Parameters: json.RawMessage(`{
-					"type": "object",
-					"properties": {
-						"title": {
-							"type": "string",
-							"description": "Title of the album"
-						},
-						"artist": {
-							"type": "string",
-							"description": "Artist name"
-						},
-						"price": {
-							"type": "number",
-							"description": "Price of the album"
-						}
+			Name:        "create_album",
+			Description: "Create a new album",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"title": {
+						"type": "string",
+						"description": "Title of the album"
 					},
-					"required": ["title", "artist", "price"]
-				}`),
-			},
+					"artist": {
+						"type": "string",
+						"description": "Artist name"
+					},
+					"price": {
+						"type": "number",
+						"description": "Price of the album, with at most two decimal places"
+					},
+					"currency": {
+						"type": "string",
+						"description": "ISO 4217 currency code of the price, defaults to USD"
+					}
+				},
+				"required": ["title", "artist", "price"]
+			}`),
 		},
 		{
-			Type: openai.ToolTypeFunction,
-			Function: &openai.FunctionDefinition{
-				Name:        "update_album",
-				Description: "Update an existing album by ID",
-				Parameters: json.RawMessage(`{
-					"type": "object",
-					"properties": {
-						"id": {
-							"type": "number",
-							"description": "ID of the album to update"
-						},
-						"title": {
-							"type": "string",
-							"description": "Title of the album"
-						},
-						"artist": {
-							"type": "string",
-							"description": "Artist name"
-						},
-						"price": {
-							"type": "number",
-							"description": "Price of the album"
-						}
+			Name:        "update_album",
+			Description: "Update an existing album by ID. Only include the fields that change.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"id": {
+						"type": "number",
+						"description": "ID of the album to update"
 					},
-					"required": ["id"]
-				}`),
-			},
+					"title": {
+						"type": "string",
+						"description": "Title of the album"
+					},
+					"artist": {
+						"type": "string",
+						"description": "Artist name"
+					},
+					"price": {
+						"type": "number",
+						"description": "Price of the album, with at most two decimal places"
+					},
+					"currency": {
+						"type": "string",
+						"description": "ISO 4217 currency code of the price, defaults to USD"
+					}
+				},
+				"required": ["id"]
+			}`),
 		},
 		{
-			Type: openai.ToolTypeFunction,
-			Function: &openai.FunctionDefinition{
-				Name:        "delete_album",
-				Description: "Delete an album by ID",
-				Parameters: json.RawMessage(`{
-					"type": "object",
-					"properties": {
-						"id": {
-							"type": "number",
-							"description": "ID of the album to delete"
-						}
-					},
-					"required": ["id"]
-				}`),
-			},
+			Name:        "delete_album",
+			Description: "Delete an album by ID",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"id": {
+						"type": "number",
+						"description": "ID of the album to delete"
+					}
+				},
+				"required": ["id"]
+			}`),
 		},
 	}
 }
@@ -174,15 +203,25 @@ func (s *Service) ExecuteTool(ctx context.Context, toolName string, argsJSON str
 		return "", fmt.Errorf("failed to parse arguments: %w", err)
 	}
 
+	// Album changes made by tools are recorded as coming from chat
+	actor := album.ActorFrom(ctx)
+	actor.Source = album.SourceChat
+	ctx = album.WithActor(ctx, actor)
+
+	// Tools act with the caller's permissions, never more
+	if err := middleware.Authorize(ctx, toolPermissions[toolName]...); err != nil {
+		return forbiddenResult(err), nil
+	}
+
 	switch toolName {
 	case "get_albums":
-		return s.getAlbums(ctx, args)
+		return s.getAlbums(ctx, args, argsJSON)
 	case "get_album_by_id":
 		return s.getAlbumByID(ctx, args)
 	case "create_album":
-		return s.createAlbum(ctx, args)
+		return s.createAlbum(ctx, argsJSON)
 	case "update_album":
-		return s.updateAlbum(ctx, args)
+		return s.updateAlbum(ctx, argsJSON)
 	case "delete_album":
 		return s.deleteAlbum(ctx, args)
 	default:
@@ -190,16 +229,88 @@ func (s *Service) ExecuteTool(ctx context.Context, toolName string, argsJSON str
 	}
 }
 
+// toolPermissions are the permissions each tool needs up front. Updates are
+// also authorized against the change they make, since changing a price needs
+// more than changing a title.
+var toolPermissions = map[string][]middleware.Permission{
+	"get_albums":      {middleware.PermAlbumsRead},
+	"get_album_by_id": {middleware.PermAlbumsRead},
+	"create_album":    {middleware.PermAlbumsCreate},
+	"update_album":    {middleware.PermAlbumsUpdate},
+	"delete_album":    {middleware.PermAlbumsDelete},
+}
+
+// checkProposal returns the tool result to report instead of offering a
+// tool call for confirmation, or "" if the caller could confirm it. Updates
+// are checked against the change they propose.
+func (s *Service) checkProposal(ctx context.Context, toolCall ToolCall) string {
+	if err := middleware.Authorize(ctx, toolPermissions[toolCall.Function.Name]...); err != nil {
+		return forbiddenResult(err)
+	}
+	if toolCall.Function.Name != "update_album" {
+		return ""
+	}
+
+	before, after, problem, err := s.proposeUpdate(ctx, toolCall.Function.Arguments)
+	if err != nil {
+		return fmt.Sprintf(`{"error": %q}`, err.Error())
+	}
+	if problem != "" {
+		return problem
+	}
+	if err := album.AuthorizeChange(ctx, before, after); err != nil {
+		return forbiddenResult(err)
+	}
+	return ""
+}
+
+// forbiddenResult reports a permission failure to the model, so it can tell
+// the user rather than retry
+func forbiddenResult(err error) string {
+	return fmt.Sprintf(`{"error": %q}`, middleware.ForbiddenMessage(err)+"; the user is not allowed to do this")
+}
+
 // Tool implementation functions
-func (s *Service) getAlbums(ctx context.Context, args map[string]interface{}) (string, error) {
-	albums, err := s.albumRepo.FindAll(ctx)
+func (s *Service) getAlbums(ctx context.Context, args map[string]interface{}, argsJSON string) (string, error) {
+	opts := album.ListOptions{Limit: defaultToolLimit}
+
+	// Prices are decoded from the raw arguments so they stay exact
+	var prices struct {
+		MinPrice *album.Money `json:"min_price"`
+		MaxPrice *album.Money `json:"max_price"`
+	}
+	if err := json.Unmarshal([]byte(argsJSON), &prices); err != nil {
+		return fmt.Sprintf(`{"error": %q}`, err.Error()), nil
+	}
+	opts.MinPrice = prices.MinPrice
+	opts.MaxPrice = prices.MaxPrice
+
+	if search, ok := args["search"].(string); ok {
+		opts.Search = search
+	}
+	if currency, ok := args["currency"].(string); ok {
+		opts.PriceCurrency = currency
+	}
+	if limit, ok := args["limit"].(float64); ok && limit > 0 {
+		opts.Limit = int(limit)
+	}
+	if opts.Limit > maxToolLimit {
+		opts.Limit = maxToolLimit
+	}
+
+	page, err := s.albumRepo.List(ctx, opts)
 	if err != nil {
+		if errors.Is(err, album.ErrInvalidQuery) {
+			return fmt.Sprintf(`{"error": %q}`, err.Error()), nil
+		}
 		return "", err
 	}
 
 	result := map[string]interface{}{
-		"albums": albums,
-		"count":  len(albums),
+		"albums":    page.Albums,
+		"count":     len(page.Albums),
+		"total":     page.Total,
+		"truncated": page.Total > len(page.Albums),
 	}
 
 	jsonData, err := json.Marshal(result)
@@ -234,26 +345,41 @@ func (s *Service) getAlbumByID(ctx context.Context, args map[string]interface{})
 	return string(jsonData), nil
 }
 
-func (s *Service) createAlbum(ctx context.Context, args map[string]interface{}) (string, error) {
-	title, ok := args["title"].(string)
-	if !ok {
-		return "", errors.New("title must be a string")
+func (s *Service) createAlbum(ctx context.Context, argsJSON string) (string, error) {
+	var params struct {
+		Title    *string      `json:"title"`
+		Artist   *string      `json:"artist"`
+		Price    *album.Money `json:"price"`
+		Currency string       `json:"currency"`
 	}
-
-	artist, ok := args["artist"].(string)
-	if !ok {
-		return "", errors.New("artist must be a string")
+	if err := json.Unmarshal([]byte(argsJSON), &params); err != nil {
+		if errors.Is(err, album.ErrInvalidMoney) {
+			return fmt.Sprintf(`{"error": %q}`, err.Error()), nil
+		}
+		return "", fmt.Errorf("failed to parse arguments: %w", err)
 	}
 
-	price, ok := args["price"].(float64)
-	if !ok {
+	switch {
+	case params.Title == nil:
+		return "", errors.New("title must be a string")
+	case params.Artist == nil:
+		return "", errors.New("artist must be a string")
+	case params.Price == nil:
 		return "", errors.New("price must be a number")
 	}
 
 	newAlbum := &album.Album{
-		Title:  title,
-		Artist: artist,
-		Price:  price,
+		Title:    *params.Title,
+		Artist:   *params.Artist,
+		Price:    *params.Price,
+		Currency: params.Currency,
+	}
+	if err := newAlbum.NormalizePricing(); err != nil {
+		return fmt.Sprintf(`{"error": %q}`, err.Error()), nil
+	}
+
+	if err := album.AuthorizeChange(ctx, nil, newAlbum); err != nil {
+		return forbiddenResult(err), nil
 	}
 
 	if err := s.albumRepo.Create(ctx, newAlbum); err != nil {
@@ -273,32 +399,56 @@ func (s *Service) createAlbum(ctx context.Context, args map[string]interface{})
 	return string(jsonData), nil
 }
 
-func (s *Service) updateAlbum(ctx context.Context, args map[string]interface{}) (string, error) {
-	id, ok := args["id"].(float64)
-	if !ok {
-		return "", errors.New("id must be a number")
+// proposeUpdate loads the album an update_album call targets and applies
+// the call to a copy. A non-empty problem is an error to report to the model.
+func (s *Service) proposeUpdate(ctx context.Context, argsJSON string) (before, after *album.Album, problem string, err error) {
+	// Optional fields follow the same rules as PATCH /albums/:id
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(argsJSON), &fields); err != nil {
+		return nil, nil, "", fmt.Errorf("failed to parse arguments: %w", err)
+	}
+
+	var id int
+	if err := json.Unmarshal(fields["id"], &id); err != nil {
+		return nil, nil, "", errors.New("id must be a number")
+	}
+	delete(fields, "id")
+
+	patch, err := album.PatchFromFields(fields)
+	if err != nil {
+		return nil, nil, fmt.Sprintf(`{"error": %q}`, err.Error()), nil
 	}
 
-	existingAlbum, err := s.albumRepo.FindByID(ctx, int(id))
+	after, err = s.albumRepo.FindByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, album.ErrNotFound) {
-			return fmt.Sprintf(`{"error": "Album with ID %d not found"}`, int(id)), nil
+			return nil, nil, fmt.Sprintf(`{"error": "Album with ID %d not found"}`, id), nil
 		}
-		return "", err
+		return nil, nil, "", err
 	}
 
-	// Update fields if provided
-	if title, ok := args["title"].(string); ok {
-		existingAlbum.Title = title
+	original := *after
+	if err := patch.Apply(after); err != nil {
+		return nil, nil, fmt.Sprintf(`{"error": %q}`, err.Error()), nil
 	}
-	if artist, ok := args["artist"].(string); ok {
-		existingAlbum.Artist = artist
+
+	return &original, after, "", nil
+}
+
+func (s *Service) updateAlbum(ctx context.Context, argsJSON string) (string, error) {
+	before, existingAlbum, problem, err := s.proposeUpdate(ctx, argsJSON)
+	if err != nil || problem != "" {
+		return problem, err
 	}
-	if price, ok := args["price"].(float64); ok {
-		existingAlbum.Price = price
+
+	if err := album.AuthorizeChange(ctx, before, existingAlbum); err != nil {
+		return forbiddenResult(err), nil
 	}
 
 	if err := s.albumRepo.Update(ctx, existingAlbum); err != nil {
+		if errors.Is(err, album.ErrVersionConflict) {
+			return fmt.Sprintf(`{"error": "Album with ID %d was changed by someone else, fetch it again before updating"}`, existingAlbum.ID), nil
+		}
 		return "", err
 	}
 
@@ -321,7 +471,7 @@ func (s *Service) deleteAlbum(ctx context.Context, args map[string]interface{})
 		return "", errors.New("id must be a number")
 	}
 
-	if err := s.albumRepo.Delete(ctx, int(id)); err != nil {
+	if err := s.albumRepo.Delete(ctx, int(id), 0); err != nil {
 		if errors.Is(err, album.ErrNotFound) {
 			return fmt.Sprintf(`{"error": "Album with ID %d not found"}`, int(id)), nil
 		}
@@ -340,93 +490,89 @@ func (s *Service) deleteAlbum(ctx context.Context, args map[string]interface{})
 	return string(jsonData), nil
 }
 
-// Chat handles the main chat interaction
-func (s *Service) Chat(ctx context.Context, messages []Message) (*ChatResponse, error) {
-	// Convert messages to OpenAI format
-	chatMessages := []openai.ChatCompletionMessage{
-		{
-			Role: openai.ChatMessageRoleSystem,
-			Content: `You are an intelligent album management assistant. You can help users manage their album collection by:
+// systemPrompt instructs the model how to behave as the album assistant
+const systemPrompt = `You are an intelligent album management assistant. You can help users manage their album collection by:
 - Viewing and searching albums
 - Creating new albums
 - Updating existing albums
 - Deleting albums
 
-Always be helpful and provide clear explanations of what actions you're taking. When presenting data, format it in a user-friendly way. If asked to create or update albums, ask for clarification on any required fields that are missing (title, artist, price are required).`,
-		},
-	}
+Updating and deleting albums may require the user to confirm the action in the interface before it takes effect. When a tool reports that an action is pending confirmation, explain what will change and do not claim it has been done.
+
+Always be helpful and provide clear explanations of what actions you're taking. When presenting data, format it in a user-friendly way. If asked to create or update albums, ask for clarification on any required fields that are missing (title, artist, price are required).`
+
+// toCompletionMessages converts the client's messages to provider form
+func toCompletionMessages(messages []Message) []CompletionMessage {
+	chatMessages := make([]CompletionMessage, 0, len(messages))
 
-	// Add user messages
 	for _, msg := range messages {
-		chatMessages = append(chatMessages, openai.ChatCompletionMessage{
+		chatMessages = append(chatMessages, CompletionMessage{
 			Role:    msg.Role,
 			Content: msg.Content,
 		})
 	}
 
-	// Make initial API call
-	resp, err := s.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model:    openai.GPT4oMini,
-		Messages: chatMessages,
-		Tools:    s.GetToolDefinitions(),
-	})
-
-	if err != nil {
-		return nil, fmt.Errorf("OpenAI API error: %w", err)
-	}
-
-	message := resp.Choices[0].Message
-
-	// Handle tool calls if present
-	if len(message.ToolCalls) > 0 {
-		toolResults := make([]ToolResult, 0, len(message.ToolCalls))
+	return chatMessages
+}
 
-		// Execute each tool call
-		for _, toolCall := range message.ToolCalls {
-			result, err := s.ExecuteTool(ctx, toolCall.Function.Name, toolCall.Function.Arguments)
+// executeToolCalls runs each tool call allowed by policy, reporting failures
+// back to the model as JSON errors. Calls that need confirmation are returned
+// as pending actions instead of being run.
+func (s *Service) executeToolCalls(ctx context.Context, toolCalls []ToolCall) ([]ToolResult, []PendingAction) {
+	toolResults := make([]ToolResult, 0, len(toolCalls))
+	var pending []PendingAction
+
+	for _, toolCall := range toolCalls {
+		var result string
+		switch s.policyFor(toolCall.Function.Name) {
+		case PolicyDeny:
+			result = fmt.Sprintf(`{"error": "Tool %s is disabled by policy"}`, toolCall.Function.Name)
+		case PolicyConfirm:
+			// Do not offer the user an action they would not be allowed to confirm
+			if result = s.checkProposal(ctx, toolCall); result != "" {
+				break
+			}
+			action, err := s.actions.sign(toolCall, actionOwnerFrom(ctx), conversationFrom(ctx), time.Now())
+			if err != nil {
+				result = fmt.Sprintf(`{"error": "%s"}`, err.Error())
+				break
+			}
+			pending = append(pending, action)
+			result = `{"status": "pending_confirmation", "message": "This action has not been performed yet. Tell the user what will happen and ask them to confirm it."}`
+		default:
+			var err error
+			result, err = s.ExecuteTool(ctx, toolCall.Function.Name, toolCall.Function.Arguments)
 			if err != nil {
 				result = fmt.Sprintf(`{"error": "%s"}`, err.Error())
 			}
-
-			toolResults = append(toolResults, ToolResult{
-				ToolCallID: toolCall.ID,
-				Output:     result,
-			})
-		}
-
-		// Add assistant's message with tool calls
-		chatMessages = append(chatMessages, message)
-
-		// Add tool results as messages
-		for _, toolResult := range toolResults {
-			chatMessages = append(chatMessages, openai.ChatCompletionMessage{
-				Role:       openai.ChatMessageRoleTool,
-				Content:    toolResult.Output,
-				ToolCallID: toolResult.ToolCallID,
-			})
 		}
 
-		// Make second API call with tool results
-		finalResp, err := s.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-			Model:    openai.GPT4oMini,
-			Messages: chatMessages,
+		toolResults = append(toolResults, ToolResult{
+			ToolCallID: toolCall.ID,
+			Output:     result,
 		})
+	}
 
-		if err != nil {
-			return nil, fmt.Errorf("OpenAI API error on second call: %w", err)
-		}
+	return toolResults, pending
+}
 
-		return &ChatResponse{
-			Message:     finalResp.Choices[0].Message.Content,
-			ToolCalls:   message.ToolCalls,
-			ToolResults: toolResults,
-		}, nil
+// appendToolResults adds the assistant's tool-call message and the tool outputs to the conversation
+func appendToolResults(chatMessages []CompletionMessage, message CompletionMessage, toolResults []ToolResult) []CompletionMessage {
+	chatMessages = append(chatMessages, message)
+
+	for _, toolResult := range toolResults {
+		chatMessages = append(chatMessages, CompletionMessage{
+			Role:       RoleTool,
+			Content:    toolResult.Output,
+			ToolCallID: toolResult.ToolCallID,
+		})
 	}
 
-	// No tool calls, return direct response
-	return &ChatResponse{
-		Message:     message.Content,
-		ToolCalls:   nil,
-		ToolResults: nil,
-	}, nil
+	return chatMessages
+}
+
+// Chat handles the main chat interaction
+func (s *Service) Chat(ctx context.Context, messages []Message) (*ChatResponse, error) {
+	response, _, err := s.runAgent(ctx, toCompletionMessages(messages), s.provider.CreateCompletion, func(StreamEvent) {})
+	return response, err
 }
@@ -0,0 +1,414 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"web-service-gin/backend/internal/album"
+	"web-service-gin/backend/internal/middleware"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRepo is an in-memory album.Repository for offline chat tests.
+// Methods the tests do not exercise fall through to the nil embedded
+// interface and panic.
+type memoryRepo struct {
+	album.Repository
+	albums []album.Album
+	// actor is who the last update was attributed to
+	actor album.Actor
+}
+
+func (r *memoryRepo) List(ctx context.Context, opts album.ListOptions) (*album.ListResult, error) {
+	result := &album.ListResult{Albums: []album.Album{}, Limit: opts.Limit}
+	for _, a := range r.albums {
+		search := strings.ToLower(opts.Search)
+		if search != "" && !strings.Contains(strings.ToLower(a.Title+" "+a.Artist), search) {
+			continue
+		}
+		result.Total++
+		if len(result.Albums) < opts.Limit {
+			result.Albums = append(result.Albums, a)
+		}
+	}
+	return result, nil
+}
+
+func (r *memoryRepo) FindByID(ctx context.Context, id int) (*album.Album, error) {
+	for i := range r.albums {
+		if r.albums[i].ID == id {
+			found := r.albums[i]
+			return &found, nil
+		}
+	}
+	return nil, album.ErrNotFound
+}
+
+func (r *memoryRepo) Delete(ctx context.Context, id int, version int) error {
+	for i := range r.albums {
+		if r.albums[i].ID == id {
+			r.albums = append(r.albums[:i], r.albums[i+1:]...)
+			return nil
+		}
+	}
+	return album.ErrNotFound
+}
+
+func (r *memoryRepo) Update(ctx context.Context, a *album.Album) error {
+	r.actor = album.ActorFrom(ctx)
+	for i := range r.albums {
+		if r.albums[i].ID == a.ID {
+			r.albums[i] = *a
+			return nil
+		}
+	}
+	return album.ErrNotFound
+}
+
+func newTestService(steps []FakeStep) *Service {
+	return newTestServiceWithConfig(steps, Config{})
+}
+
+func newTestServiceWithConfig(steps []FakeStep, config Config) *Service {
+	repo := &memoryRepo{albums: []album.Album{
+		{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 5699},
+		{ID: 2, Title: "Giant Steps", Artist: "John Coltrane", Price: 1799},
+		{ID: 3, Title: "The Wall", Artist: "Pink Floyd", Price: 2499},
+	}}
+	return NewService(NewFakeProvider(steps), repo, newMemoryConversations(repo), config)
+}
+
+func TestService_Chat_NoTools(t *testing.T) {
+	service := newTestService(nil)
+
+	response, err := service.Chat(context.Background(), []Message{{Role: RoleUser, Content: "hello"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "You said: hello", response.Message)
+	assert.Empty(t, response.ToolCalls)
+}
+
+func TestService_Chat_ToolCall(t *testing.T) {
+	service := newTestService([]FakeStep{
+		{ToolCalls: []FakeToolCall{{Name: "get_albums", Arguments: json.RawMessage(`{"search": "coltrane", "limit": 1}`)}}},
+		{Content: "Here are the Coltrane albums."},
+	})
+
+	response, err := service.Chat(context.Background(), []Message{{Role: RoleUser, Content: "Show me Coltrane"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Here are the Coltrane albums.", response.Message)
+	require.Len(t, response.ToolCalls, 1)
+	assert.Equal(t, "get_albums", response.ToolCalls[0].Function.Name)
+	require.Len(t, response.ToolResults, 1)
+	assert.Equal(t, response.ToolCalls[0].ID, response.ToolResults[0].ToolCallID)
+
+	var output map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(response.ToolResults[0].Output), &output))
+	assert.Equal(t, float64(1), output["count"])
+	assert.Equal(t, float64(2), output["total"])
+	assert.Equal(t, true, output["truncated"])
+}
+
+func TestService_ChatStream(t *testing.T) {
+	service := newTestService([]FakeStep{
+		{ToolCalls: []FakeToolCall{{Name: "get_album_by_id", Arguments: json.RawMessage(`{"id": 3}`)}}},
+		{Content: "That is The Wall."},
+	})
+
+	var events []StreamEvent
+	err := service.ChatStream(context.Background(), []Message{{Role: RoleUser, Content: "What is album 3?"}}, func(event StreamEvent) {
+		events = append(events, event)
+	})
+	require.NoError(t, err)
+
+	var types []string
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []string{EventToolCall, EventToolResult, EventDelta, EventDelta, EventDelta, EventDelta, EventDone}, types)
+
+	done, ok := events[len(events)-1].Data.(*ChatResponse)
+	require.True(t, ok)
+	assert.Equal(t, "That is The Wall.", done.Message)
+	assert.Contains(t, done.ToolResults[0].Output, "Pink Floyd")
+}
+
+func TestService_Chat_MultiStep(t *testing.T) {
+	service := newTestService([]FakeStep{
+		{ToolCalls: []FakeToolCall{{Name: "get_albums", Arguments: json.RawMessage(`{"search": "coltrane"}`)}}},
+		{ToolCalls: []FakeToolCall{
+			{Name: "get_album_by_id", Arguments: json.RawMessage(`{"id": 1}`)},
+			{Name: "get_album_by_id", Arguments: json.RawMessage(`{"id": 2}`)},
+		}},
+		{Content: "Both albums found."},
+	})
+
+	response, err := service.Chat(context.Background(), []Message{{Role: RoleUser, Content: "Look up every Coltrane album"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Both albums found.", response.Message)
+	assert.False(t, response.StepLimitReached)
+	require.Len(t, response.Steps, 3)
+	assert.Len(t, response.Steps[0].ToolCalls, 1)
+	assert.Len(t, response.Steps[1].ToolResults, 2)
+	assert.Len(t, response.ToolCalls, 3)
+	assert.Len(t, response.ToolResults, 3)
+}
+
+func TestService_Chat_StepLimit(t *testing.T) {
+	search := FakeStep{ToolCalls: []FakeToolCall{{Name: "get_albums"}}}
+	service := newTestServiceWithConfig([]FakeStep{search, search, search}, Config{MaxSteps: 2})
+
+	response, err := service.Chat(context.Background(), []Message{{Role: RoleUser, Content: "loop"}})
+	require.NoError(t, err)
+
+	assert.True(t, response.StepLimitReached)
+	assert.Len(t, response.ToolCalls, 2)
+	require.Len(t, response.Steps, 3)
+	assert.Contains(t, response.Message, "You said: loop")
+	assert.Equal(t, response.Message, response.Steps[2].Content)
+}
+
+func TestService_Chat_ConfirmDestructiveTool(t *testing.T) {
+	service := newTestService([]FakeStep{
+		{ToolCalls: []FakeToolCall{{Name: "delete_album", Arguments: json.RawMessage(`{"id": 3}`)}}},
+		{Content: "Please confirm deleting The Wall."},
+	})
+	repo := service.albumRepo.(*memoryRepo)
+
+	response, err := service.Chat(context.Background(), []Message{{Role: RoleUser, Content: "Delete album 3"}})
+	require.NoError(t, err)
+
+	// Nothing is deleted until the action is confirmed
+	require.Len(t, response.PendingActions, 1)
+	assert.Equal(t, "delete_album", response.PendingActions[0].Tool)
+	assert.Contains(t, response.ToolResults[0].Output, "pending_confirmation")
+	assert.Len(t, repo.albums, 3)
+
+	token := response.PendingActions[0].Token
+
+	// A tampered token is rejected
+	_, err = service.ConfirmAction(context.Background(), token+"x")
+	assert.ErrorIs(t, err, ErrInvalidActionToken)
+
+	result, err := service.ConfirmAction(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, "delete_album", result.Tool)
+	assert.Len(t, repo.albums, 2)
+
+	// Tokens are single use
+	_, err = service.ConfirmAction(context.Background(), token)
+	assert.ErrorIs(t, err, ErrActionUsed)
+}
+
+func TestService_Chat_DenyPolicy(t *testing.T) {
+	service := newTestServiceWithConfig([]FakeStep{
+		{ToolCalls: []FakeToolCall{{Name: "delete_album", Arguments: json.RawMessage(`{"id": 3}`)}}},
+		{Content: "I cannot delete albums."},
+	}, Config{ToolPolicies: map[string]Policy{"delete_album": PolicyDeny}})
+
+	response, err := service.Chat(context.Background(), []Message{{Role: RoleUser, Content: "Delete album 3"}})
+	require.NoError(t, err)
+
+	assert.Empty(t, response.PendingActions)
+	assert.Contains(t, response.ToolResults[0].Output, "disabled by policy")
+	assert.Len(t, service.albumRepo.(*memoryRepo).albums, 3)
+}
+
+func TestService_ExecuteTool_UpdateAlbum(t *testing.T) {
+	service := newTestService(nil)
+
+	// A zero price is a value, not a missing field
+	output, err := service.ExecuteTool(context.Background(), "update_album", `{"id": 1, "price": 0}`)
+	require.NoError(t, err)
+	assert.Contains(t, output, `"price":0`)
+	assert.Contains(t, output, `"title":"Blue Train"`)
+
+	// Invalid fields are reported back to the model
+	output, err = service.ExecuteTool(context.Background(), "update_album", `{"id": 1, "title": null}`)
+	require.NoError(t, err)
+	assert.Contains(t, output, "title cannot be null")
+
+	// Changes keep the caller's name but are recorded as coming from chat
+	ctx := album.WithActor(context.Background(), album.Actor{Name: "alice", Source: album.SourceREST})
+	_, err = service.ExecuteTool(ctx, "update_album", `{"id": 2, "price": 9.99}`)
+	require.NoError(t, err)
+	assert.Equal(t, album.Actor{Name: "alice", Source: album.SourceChat}, service.albumRepo.(*memoryRepo).actor)
+}
+
+func TestService_ExecuteTool_Permissions(t *testing.T) {
+	service := newTestService(nil)
+	repo := service.albumRepo.(*memoryRepo)
+	intern := middleware.WithPrincipal(context.Background(), &middleware.Principal{Subject: "sam", Roles: []string{middleware.RoleIntern}})
+
+	// Interns may browse and rename
+	output, err := service.ExecuteTool(intern, "get_album_by_id", `{"id": 1}`)
+	require.NoError(t, err)
+	assert.Contains(t, output, `"title":"Blue Train"`)
+
+	_, err = service.ExecuteTool(intern, "update_album", `{"id": 1, "title": "Blue Train (Mono)"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "Blue Train (Mono)", repo.albums[0].Title)
+
+	// but not reprice, create or delete, however the model asks
+	tests := []struct {
+		tool string
+		args string
+	}{
+		{"update_album", `{"id": 1, "price": 0.99}`},
+		{"update_album", `{"id": 1, "title": "Blue Train", "currency": "EUR"}`},
+		{"create_album", `{"title": "Kind of Blue", "artist": "Miles Davis", "price": 19.99}`},
+		{"delete_album", `{"id": 1}`},
+	}
+
+	for _, tt := range tests {
+		output, err := service.ExecuteTool(intern, tt.tool, tt.args)
+		require.NoError(t, err)
+		assert.Contains(t, output, "Missing required permission", tt.args)
+	}
+	assert.Len(t, repo.albums, 3)
+	assert.Equal(t, album.Money(5699), repo.albums[0].Price)
+	assert.Equal(t, "Blue Train (Mono)", repo.albums[0].Title)
+}
+
+func TestService_Chat_ConfirmRequiresPermission(t *testing.T) {
+	service := newTestService([]FakeStep{
+		{ToolCalls: []FakeToolCall{{Name: "delete_album", Arguments: json.RawMessage(`{"id": 3}`)}}},
+		{Content: "You are not allowed to delete albums."},
+	})
+	intern := middleware.WithPrincipal(context.Background(), &middleware.Principal{Subject: "sam", Roles: []string{middleware.RoleIntern}})
+
+	// Actions the caller may not take are not offered for confirmation
+	response, err := service.Chat(intern, []Message{{Role: RoleUser, Content: "Delete album 3"}})
+	require.NoError(t, err)
+	assert.Empty(t, response.PendingActions)
+	assert.Contains(t, response.ToolResults[0].Output, "Missing required permission")
+
+	// and confirming one checks the permissions of whoever confirms it
+	action, err := service.actions.sign(ToolCall{ID: "call_1", Function: FunctionCall{Name: "delete_album", Arguments: `{"id": 3}`}}, actionOwnerFrom(intern), 0, time.Now())
+	require.NoError(t, err)
+
+	result, err := service.ConfirmAction(intern, action.Token)
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "Missing required permission")
+	assert.Len(t, service.albumRepo.(*memoryRepo).albums, 3)
+}
+
+func TestService_Chat_ConfirmUpdateRequiresPermission(t *testing.T) {
+	tests := []struct {
+		name      string
+		role      string
+		arguments string
+		offered   bool
+	}{
+		{"viewer renaming", middleware.RoleViewer, `{"id": 1, "title": "Blue"}`, false},
+		{"intern renaming", middleware.RoleIntern, `{"id": 1, "title": "Blue"}`, true},
+		{"intern repricing", middleware.RoleIntern, `{"id": 1, "price": 9.99}`, false},
+		{"editor repricing", middleware.RoleEditor, `{"id": 1, "price": 9.99}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestService([]FakeStep{
+				{ToolCalls: []FakeToolCall{{Name: "update_album", Arguments: json.RawMessage(tt.arguments)}}},
+				{Content: "Done."},
+			})
+			ctx := middleware.WithPrincipal(context.Background(), &middleware.Principal{Subject: "sam", Roles: []string{tt.role}})
+
+			response, err := service.Chat(ctx, []Message{{Role: RoleUser, Content: "Change album 1"}})
+			require.NoError(t, err)
+			if tt.offered {
+				assert.Len(t, response.PendingActions, 1)
+				return
+			}
+			assert.Empty(t, response.PendingActions)
+			assert.Contains(t, response.ToolResults[0].Output, "Missing required permission")
+		})
+	}
+}
+
+func TestService_ExecuteTool_CreateAlbumPrice(t *testing.T) {
+	service := newTestService(nil)
+
+	output, err := service.ExecuteTool(context.Background(), "create_album", `{"title": "Kind of Blue", "artist": "Miles Davis", "price": 19.999}`)
+	require.NoError(t, err)
+	assert.Contains(t, output, "more than two decimal places")
+}
+
+func TestActionSigner_Expired(t *testing.T) {
+	signer := newActionSigner([]byte("secret"), time.Minute)
+	now := time.Now()
+
+	action, err := signer.sign(ToolCall{ID: "call_1", Function: FunctionCall{Name: "delete_album", Arguments: `{"id": 1}`}}, actionOwner{Tenant: "acme"}, 0, now)
+	require.NoError(t, err)
+
+	_, err = signer.verify(action.Token, actionOwner{Tenant: "acme"}, now.Add(2*time.Minute))
+	assert.ErrorIs(t, err, ErrActionExpired)
+
+	// A different secret cannot verify the token
+	_, err = newActionSigner([]byte("other"), time.Minute).verify(action.Token, actionOwner{Tenant: "acme"}, now)
+	assert.ErrorIs(t, err, ErrInvalidActionToken)
+}
+
+func TestService_ConfirmAction_OtherTenant(t *testing.T) {
+	service := newTestService(nil)
+	acme := middleware.WithTenant(context.Background(), "acme")
+
+	action, err := service.actions.sign(ToolCall{ID: "call_1", Function: FunctionCall{Name: "delete_album", Arguments: `{"id": 1}`}}, actionOwner{Tenant: "acme"}, 0, time.Now())
+	require.NoError(t, err)
+
+	_, err = service.ConfirmAction(middleware.WithTenant(context.Background(), "globex"), action.Token)
+	assert.ErrorIs(t, err, ErrActionTenant)
+
+	// The refused attempt does not use the token up
+	result, err := service.ConfirmAction(acme, action.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "delete_album", result.Tool)
+
+	_, err = service.ConfirmAction(acme, action.Token)
+	assert.ErrorIs(t, err, ErrActionUsed)
+}
+
+func TestService_ConfirmAction_OtherUser(t *testing.T) {
+	service := newTestService(nil)
+	as := func(subject string) context.Context {
+		return middleware.WithPrincipal(context.Background(), &middleware.Principal{Subject: subject, Roles: []string{middleware.RoleEditor}})
+	}
+
+	action, err := service.actions.sign(ToolCall{ID: "call_1", Function: FunctionCall{Name: "delete_album", Arguments: `{"id": 1}`}}, actionOwnerFrom(as("sam")), 0, time.Now())
+	require.NoError(t, err)
+
+	// The token is bound to the user it was proposed to, even within a tenant
+	_, err = service.ConfirmAction(as("alex"), action.Token)
+	assert.ErrorIs(t, err, ErrActionSubject)
+	_, err = service.RejectAction(context.Background(), action.Token)
+	assert.ErrorIs(t, err, ErrActionSubject)
+
+	result, err := service.ConfirmAction(as("sam"), action.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "delete_album", result.Tool)
+}
+
+func TestService_ConfirmAction_AcrossReplicas(t *testing.T) {
+	repo := &memoryRepo{albums: []album.Album{{ID: 1, Title: "Blue Train", Artist: "John Coltrane", Price: 5699}}}
+	conversations := newMemoryConversations(repo)
+	config := Config{ActionSecret: []byte("shared-secret")}
+	first := NewService(NewFakeProvider(nil), repo, conversations, config)
+	second := NewService(NewFakeProvider(nil), repo, conversations, config)
+
+	action, err := first.actions.sign(ToolCall{ID: "call_1", Function: FunctionCall{Name: "delete_album", Arguments: `{"id": 1}`}}, actionOwner{Tenant: middleware.DefaultTenant}, 0, time.Now())
+	require.NoError(t, err)
+
+	_, err = first.ConfirmAction(context.Background(), action.Token)
+	require.NoError(t, err)
+
+	// Redemptions live in the repository, not the process that confirmed
+	_, err = second.ConfirmAction(context.Background(), action.Token)
+	assert.ErrorIs(t, err, ErrActionUsed)
+}
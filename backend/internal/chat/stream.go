@@ -0,0 +1,62 @@
+package chat
+
+import (
+	"context"
+)
+
+// Stream event types sent to the client
+const (
+	EventDelta          = "delta"
+	EventToolCall       = "tool_call"
+	EventToolResult     = "tool_result"
+	EventActionRequired = "action_required"
+	EventDone           = "done"
+	EventError          = "error"
+)
+
+// StreamEvent is a single Server-Sent Event emitted while streaming a chat
+type StreamEvent struct {
+	Type string
+	Data interface{}
+}
+
+// DeltaEvent carries a fragment of the assistant's reply
+type DeltaEvent struct {
+	Content string `json:"content"`
+}
+
+// ToolCallEvent announces that the assistant is about to run a tool
+type ToolCallEvent struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// ErrorEvent reports a failure after the stream has started
+type ErrorEvent struct {
+	Error string `json:"error"`
+}
+
+// ChatStream handles a chat interaction, emitting events as the reply is generated.
+// The final event is always either EventDone with the full ChatResponse or EventError.
+func (s *Service) ChatStream(ctx context.Context, messages []Message, emit func(StreamEvent)) error {
+	response, _, err := s.runAgent(ctx, toCompletionMessages(messages), s.streamingComplete(emit), emit)
+	if err != nil {
+		emit(StreamEvent{Type: EventError, Data: ErrorEvent{Error: err.Error()}})
+		return err
+	}
+
+	emit(StreamEvent{Type: EventDone, Data: response})
+	return nil
+}
+
+// streamingComplete returns a completeFunc that streams content deltas as events
+func (s *Service) streamingComplete(emit func(StreamEvent)) completeFunc {
+	onDelta := func(content string) {
+		emit(StreamEvent{Type: EventDelta, Data: DeltaEvent{Content: content}})
+	}
+
+	return func(ctx context.Context, req CompletionRequest) (CompletionMessage, error) {
+		return s.provider.StreamCompletion(ctx, req, onDelta)
+	}
+}
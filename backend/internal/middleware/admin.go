@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminTokenHeader carries the shared admin token
+const AdminTokenHeader = "X-Admin-Token"
+
+// HasAdminToken reports whether the request carries the configured admin
+// token. An empty admin token matches nothing.
+func HasAdminToken(c *gin.Context, adminToken string) bool {
+	token := c.GetHeader(AdminTokenHeader)
+	if adminToken == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+}
+
+// RequireAdminToken is middleware that responds 403 with the given message
+// unless the request carries the admin token
+func RequireAdminToken(adminToken, message string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasAdminToken(c, adminToken) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": message})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,326 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultAuthLeeway absorbs clock skew when checking token expiry
+const DefaultAuthLeeway = time.Minute
+
+// Authentication methods
+const (
+	AuthMethodJWT    = "jwt"
+	AuthMethodAPIKey = "api_key"
+)
+
+// Scopes granted to API keys and tokens
+const (
+	ScopeAlbumsRead  = "albums:read"
+	ScopeAlbumsWrite = "albums:write"
+	ScopeChat        = "chat"
+)
+
+// APIKeyPrefix starts every API key, which tells keys sent as bearer tokens
+// apart from JWTs
+const APIKeyPrefix = "alb_"
+
+// Scopes lists every scope the service checks
+var Scopes = []string{ScopeAlbumsRead, ScopeAlbumsWrite, ScopeChat}
+
+var (
+	// ErrInvalidAPIKey is returned when an API key is malformed or unknown
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	// ErrAPIKeyExpired is returned when an API key is past its expiry
+	ErrAPIKeyExpired = errors.New("API key expired")
+	// ErrAPIKeyRevoked is returned when an API key has been revoked
+	ErrAPIKeyRevoked = errors.New("API key revoked")
+)
+
+// APIKeyVerifier looks up API keys and returns the principal they stand for
+type APIKeyVerifier interface {
+	VerifyAPIKey(ctx context.Context, key string) (*Principal, error)
+}
+
+// principalKey is the Gin context key holding the authenticated Principal
+const principalKey = "principal"
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Subject string
+	Scopes  []string
+	Roles   []string
+	// Tenant binds the caller to one tenant. Empty lets the request pick one.
+	Tenant string
+	// Method is how the caller authenticated
+	Method string
+}
+
+// HasScope reports whether the principal was granted the scope
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// AuthConfig holds authentication settings
+type AuthConfig struct {
+	// Secret verifies HS256 tokens. Empty disables HS256.
+	Secret []byte
+	// JWKSFile is a local JSON Web Key Set whose RSA keys verify RS256
+	// tokens. Empty disables RS256.
+	JWKSFile string
+	// Issuer and Audience, when set, must match the token's iss and aud claims
+	Issuer   string
+	Audience string
+	// Leeway is the clock skew allowed when checking exp and nbf
+	Leeway time.Duration
+	// Required rejects anonymous requests even when no JWT verification is
+	// configured, e.g. when only API keys are in use
+	Required bool
+}
+
+// LoadAuthConfig reads authentication settings from the environment
+func LoadAuthConfig() (AuthConfig, error) {
+	config := AuthConfig{
+		Secret:   []byte(os.Getenv("AUTH_JWT_SECRET")),
+		JWKSFile: os.Getenv("AUTH_JWKS_FILE"),
+		Issuer:   os.Getenv("AUTH_ISSUER"),
+		Audience: os.Getenv("AUTH_AUDIENCE"),
+		Leeway:   DefaultAuthLeeway,
+	}
+
+	if len(config.Secret) > 0 && len(config.Secret) < 32 {
+		return config, fmt.Errorf("AUTH_JWT_SECRET must be at least 32 bytes")
+	}
+
+	if raw := os.Getenv("AUTH_LEEWAY"); raw != "" {
+		leeway, err := time.ParseDuration(raw)
+		if err != nil || leeway < 0 {
+			return config, fmt.Errorf("AUTH_LEEWAY must be a duration, got %q", raw)
+		}
+		config.Leeway = leeway
+	}
+
+	if raw := os.Getenv("AUTH_REQUIRED"); raw != "" {
+		required, err := strconv.ParseBool(raw)
+		if err != nil {
+			return config, fmt.Errorf("AUTH_REQUIRED must be true or false, got %q", raw)
+		}
+		config.Required = required
+	}
+
+	return config, nil
+}
+
+// Authenticator verifies the credentials sent with requests
+type Authenticator struct {
+	jwt      *jwtVerifier
+	apiKeys  APIKeyVerifier
+	required bool
+	now      func() time.Time
+}
+
+// NewAuthenticator creates an authenticator, loading the JWKS file if one is
+// configured. Unless AuthConfig.Required is set, an authenticator with
+// neither a secret nor a JWKS file lets anonymous requests through.
+func NewAuthenticator(config AuthConfig) (*Authenticator, error) {
+	var keys map[string]*rsa.PublicKey
+	if config.JWKSFile != "" {
+		var err error
+		if keys, err = loadJWKS(config.JWKSFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Authenticator{
+		jwt: &jwtVerifier{
+			secret:   config.Secret,
+			keys:     keys,
+			issuer:   config.Issuer,
+			audience: config.Audience,
+			leeway:   config.Leeway,
+		},
+		required: config.Required,
+		now:      time.Now,
+	}, nil
+}
+
+// UseAPIKeys makes the authenticator accept API keys checked by the verifier
+func (a *Authenticator) UseAPIKeys(verifier APIKeyVerifier) {
+	a.apiKeys = verifier
+}
+
+// Required reports whether requests without credentials are rejected, which
+// is the case whenever tokens can be verified or AuthConfig.Required is set
+func (a *Authenticator) Required() bool {
+	return a.required || len(a.jwt.secret) > 0 || len(a.jwt.keys) > 0
+}
+
+// Authenticate is middleware that verifies the request's credentials and
+// attaches their principal to the request. Callers send either a JWT as an
+// "Authorization: Bearer" token or an API key, as a bearer token or in the
+// X-API-Key header. Invalid credentials are answered with 401, as are
+// requests without any when authentication is required; otherwise those are
+// let through anonymously.
+func (a *Authenticator) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		credential, isAPIKey := credentials(c)
+		if credential == "" {
+			if a.Required() {
+				unauthorized(c, "", "Authentication required")
+				return
+			}
+			c.Next()
+			return
+		}
+
+		var principal *Principal
+		var err error
+		if isAPIKey {
+			principal, err = a.verifyAPIKey(c.Request.Context(), credential)
+		} else {
+			principal, err = a.jwt.verify(credential, a.now())
+		}
+		if err != nil {
+			message, ok := authErrorMessage(err)
+			if !ok {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate"})
+				return
+			}
+			unauthorized(c, "invalid_token", message)
+			return
+		}
+
+		SetPrincipal(c, principal)
+		c.Next()
+	}
+}
+
+func (a *Authenticator) verifyAPIKey(ctx context.Context, key string) (*Principal, error) {
+	if a.apiKeys == nil {
+		return nil, ErrInvalidAPIKey
+	}
+	return a.apiKeys.VerifyAPIKey(ctx, key)
+}
+
+// credentials returns the API key or bearer token sent with a request and
+// whether it is an API key
+func credentials(c *gin.Context) (string, bool) {
+	if key := strings.TrimSpace(c.GetHeader("X-API-Key")); key != "" {
+		return key, true
+	}
+	token, ok := bearerToken(c.GetHeader("Authorization"))
+	if !ok {
+		return "", false
+	}
+	return token, strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// authErrorMessage describes a rejected credential to the caller. It
+// reports false for other failures, such as a key lookup that errored.
+func authErrorMessage(err error) (string, bool) {
+	switch {
+	case errors.Is(err, ErrTokenExpired):
+		return "Token expired", true
+	case errors.Is(err, ErrAPIKeyExpired):
+		return "API key expired", true
+	case errors.Is(err, ErrAPIKeyRevoked):
+		return "API key revoked", true
+	case errors.Is(err, ErrInvalidAPIKey):
+		return "Invalid API key", true
+	case errors.Is(err, ErrInvalidToken):
+		return "Invalid token", true
+	default:
+		return "", false
+	}
+}
+
+// RequireScope is middleware that responds 403 unless an API key principal
+// has the scope. Token holders are governed by their roles instead (see
+// RequirePermission), and anonymous requests, let through when
+// authentication is not required, have no principal; neither is checked.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := PrincipalFrom(c)
+		if ok && principal.Method == AuthMethodAPIKey && !principal.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": fmt.Sprintf("Missing required scope %q", scope),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireScopeByMethod is middleware that requires readScope for safe
+// methods (GET, HEAD, OPTIONS) and writeScope for everything else
+func RequireScopeByMethod(readScope, writeScope string) gin.HandlerFunc {
+	read, write := RequireScope(readScope), RequireScope(writeScope)
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			read(c)
+		default:
+			write(c)
+		}
+	}
+}
+
+// SetPrincipal attaches the principal to the Gin context and to the request
+// context, where code outside the HTTP layer can read it
+func SetPrincipal(c *gin.Context, principal *Principal) {
+	c.Set(principalKey, principal)
+	c.Request = c.Request.WithContext(WithPrincipal(c.Request.Context(), principal))
+}
+
+// PrincipalFrom returns the authenticated caller of a request, if any
+func PrincipalFrom(c *gin.Context) (*Principal, bool) {
+	value, ok := c.Get(principalKey)
+	if !ok {
+		return nil, false
+	}
+	principal, ok := value.(*Principal)
+	return principal, ok
+}
+
+type principalContextKey struct{}
+
+// WithPrincipal returns a context carrying the principal
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal carried by ctx, if any
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer" header
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// unauthorized responds 401 with a WWW-Authenticate challenge (RFC 6750)
+func unauthorized(c *gin.Context, code, message string) {
+	challenge := `Bearer realm="albums"`
+	if code != "" {
+		challenge += fmt.Sprintf(`, error=%q`, code)
+	}
+	c.Header("WWW-Authenticate", challenge)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message})
+}
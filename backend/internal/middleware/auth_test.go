@@ -0,0 +1,303 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func signHS256(t *testing.T, secret string, claims map[string]interface{}) string {
+	unsigned := encodeSegment(t, map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + encodeSegment(t, claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	unsigned := encodeSegment(t, map[string]string{"alg": "RS256", "kid": kid}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func writeJWKS(t *testing.T, kid string, key *rsa.PublicKey) string {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	data, err := json.Marshal(map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"alg": "RS256",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func claims(overrides map[string]interface{}) map[string]interface{} {
+	c := map[string]interface{}{
+		"sub":   "alice",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": "albums:read chat",
+		"roles": []string{"editor"},
+	}
+	for k, v := range overrides {
+		c[k] = v
+	}
+	return c
+}
+
+func newAuthRouter(t *testing.T, config AuthConfig, handlers ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	authenticator, err := NewAuthenticator(config)
+	require.NoError(t, err)
+
+	router := gin.New()
+	group := router.Group("/", authenticator.Authenticate())
+	group.GET("/", append(handlers, func(c *gin.Context) {
+		principal, ok := PrincipalFrom(c)
+		if !ok {
+			c.JSON(http.StatusOK, gin.H{"subject": ""})
+			return
+		}
+		fromContext, _ := PrincipalFromContext(c.Request.Context())
+		c.JSON(http.StatusOK, gin.H{"subject": principal.Subject, "same": fromContext == principal})
+	})...)
+	return router
+}
+
+func get(router *gin.Engine, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthenticate_HS256(t *testing.T) {
+	router := newAuthRouter(t, AuthConfig{Secret: []byte(testSecret), Issuer: "albums-auth", Audience: "albums-api", Leeway: time.Minute})
+	valid := map[string]interface{}{"iss": "albums-auth", "aud": []string{"other", "albums-api"}}
+
+	w := get(router, signHS256(t, testSecret, claims(valid)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"subject": "alice", "same": true}`, w.Body.String())
+
+	tests := []struct {
+		name    string
+		token   string
+		message string
+	}{
+		{"missing", "", "Authentication required"},
+		{"malformed", "not-a-jwt", "Invalid token"},
+		{"wrong secret", signHS256(t, "another-secret-another-secret-xx", claims(valid)), "Invalid token"},
+		{"expired", signHS256(t, testSecret, claims(map[string]interface{}{
+			"iss": "albums-auth", "aud": "albums-api", "exp": time.Now().Add(-2 * time.Minute).Unix(),
+		})), "Token expired"},
+		{"wrong issuer", signHS256(t, testSecret, claims(map[string]interface{}{"iss": "elsewhere", "aud": "albums-api"})), "Invalid token"},
+		{"wrong audience", signHS256(t, testSecret, claims(map[string]interface{}{"iss": "albums-auth", "aud": "other"})), "Invalid token"},
+		{"alg none", encodeSegment(t, map[string]string{"alg": "none"}) + "." + encodeSegment(t, claims(valid)) + ".", "Invalid token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := get(router, tt.token)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.JSONEq(t, `{"error": "`+tt.message+`"}`, w.Body.String())
+			assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Bearer")
+		})
+	}
+
+	// Within the leeway an expired token is still accepted
+	w = get(router, signHS256(t, testSecret, claims(map[string]interface{}{
+		"iss": "albums-auth", "aud": "albums-api", "exp": time.Now().Add(-30 * time.Second).Unix(),
+	})))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAuthenticate_RS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwks := writeJWKS(t, "key-1", &key.PublicKey)
+	router := newAuthRouter(t, AuthConfig{JWKSFile: jwks})
+
+	assert.Equal(t, http.StatusOK, get(router, signRS256(t, key, "key-1", claims(nil))).Code)
+	assert.Equal(t, http.StatusOK, get(router, signRS256(t, key, "", claims(nil))).Code)
+	assert.Equal(t, http.StatusUnauthorized, get(router, signRS256(t, other, "key-1", claims(nil))).Code)
+	assert.Equal(t, http.StatusUnauthorized, get(router, signRS256(t, key, "key-2", claims(nil))).Code)
+
+	// HS256 is not accepted when only a JWKS is configured, so the public
+	// key cannot be used as an HMAC secret
+	assert.Equal(t, http.StatusUnauthorized, get(router, signHS256(t, string(key.PublicKey.N.Bytes()), claims(nil))).Code)
+}
+
+func TestLoadJWKS_Invalid(t *testing.T) {
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	_, err = loadJWKS(writeJWKS(t, "small", &small.PublicKey))
+	assert.Error(t, err)
+
+	_, err = loadJWKS(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+
+	empty := filepath.Join(t.TempDir(), "empty.json")
+	require.NoError(t, os.WriteFile(empty, []byte(`{"keys": [{"kty": "EC", "kid": "ec"}]}`), 0o600))
+	_, err = loadJWKS(empty)
+	assert.Error(t, err)
+}
+
+func TestRequireScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/", func(c *gin.Context) {
+		SetPrincipal(c, &Principal{Subject: "api-key:1", Scopes: []string{ScopeAlbumsRead}, Method: c.Query("method")})
+	}, RequireScope(ScopeAlbumsWrite), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?method="+AuthMethodAPIKey, nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.JSONEq(t, `{"error": "Missing required scope \"albums:write\""}`, w.Body.String())
+
+	// Token holders are authorized by their roles, not their scopes
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?method="+AuthMethodJWT, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAuthenticate_Scopes(t *testing.T) {
+	authenticator, err := NewAuthenticator(AuthConfig{Secret: []byte(testSecret)})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/", authenticator.Authenticate(), func(c *gin.Context) {
+		principal, _ := PrincipalFrom(c)
+		c.JSON(http.StatusOK, principal.Scopes)
+	})
+
+	// Scopes may also arrive in an "scp" array
+	w := get(router, signHS256(t, testSecret, claims(map[string]interface{}{"scope": nil, "scp": []string{"albums:write"}})))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `["albums:write"]`, w.Body.String())
+}
+
+func TestAuthenticate_NotRequired(t *testing.T) {
+	router := newAuthRouter(t, AuthConfig{}, RequireScope("albums:write"))
+
+	w := get(router, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"subject": ""}`, w.Body.String())
+
+	// Credentials that are sent are still checked
+	assert.Equal(t, http.StatusUnauthorized, get(router, "alb_unknown").Code)
+
+	router = newAuthRouter(t, AuthConfig{Required: true})
+	assert.Equal(t, http.StatusUnauthorized, get(router, "").Code)
+}
+
+// stubKeys accepts a single API key
+type stubKeys struct {
+	key string
+	err error
+}
+
+func (s stubKeys) VerifyAPIKey(ctx context.Context, key string) (*Principal, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if key != s.key {
+		return nil, ErrInvalidAPIKey
+	}
+	return &Principal{Subject: "api-key:1", Scopes: []string{ScopeAlbumsRead}, Method: AuthMethodAPIKey}, nil
+}
+
+func TestAuthenticate_APIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(keys APIKeyVerifier) *gin.Engine {
+		authenticator, err := NewAuthenticator(AuthConfig{Secret: []byte(testSecret)})
+		require.NoError(t, err)
+		authenticator.UseAPIKeys(keys)
+
+		router := gin.New()
+		router.Use(authenticator.Authenticate(), RequireScopeByMethod(ScopeAlbumsRead, ScopeAlbumsWrite))
+		handler := func(c *gin.Context) {
+			principal, _ := PrincipalFrom(c)
+			c.JSON(http.StatusOK, gin.H{"subject": principal.Subject, "method": principal.Method})
+		}
+		router.GET("/", handler)
+		router.POST("/", handler)
+		return router
+	}
+	router := newRouter(stubKeys{key: "alb_0123456789ab_secret"})
+
+	// In the X-API-Key header
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-API-Key", "alb_0123456789ab_secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"subject": "api-key:1", "method": "api_key"}`, w.Body.String())
+
+	// As a bearer token, next to JWTs
+	assert.Equal(t, http.StatusOK, get(router, "alb_0123456789ab_secret").Code)
+	assert.Equal(t, http.StatusOK, get(router, signHS256(t, testSecret, claims(nil))).Code)
+
+	w = get(router, "alb_0123456789ab_wrong")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.JSONEq(t, `{"error": "Invalid API key"}`, w.Body.String())
+
+	// A read-only key cannot write
+	req = httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("X-API-Key", "alb_0123456789ab_secret")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// while a token without scopes is left to its roles
+	req = httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+signHS256(t, testSecret, claims(map[string]interface{}{"scope": ""})))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = get(newRouter(stubKeys{err: ErrAPIKeyRevoked}), "alb_0123456789ab_secret")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.JSONEq(t, `{"error": "API key revoked"}`, w.Body.String())
+
+	// Lookup failures are not the caller's fault
+	w = get(newRouter(stubKeys{err: errors.New("connection refused")}), "alb_0123456789ab_secret")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
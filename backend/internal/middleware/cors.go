@@ -1,22 +1,232 @@
 package middleware
 
 import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
 )
 
-// CORS middleware allows cross-origin requests from the frontend
-func CORS() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+// Defaults used when the CORS_* variables are not set
+var (
+	DefaultCORSOrigins = []string{"http://localhost:3000"}
+	DefaultCORSMethods = []string{
+		http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions,
+	}
+	DefaultCORSHeaders = []string{
+		"Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "Accept", "Origin",
+		"Cache-Control", "X-Requested-With", "If-Match", AdminTokenHeader, "X-API-Key", TenantHeader,
+	}
+	DefaultCORSExposedHeaders = []string{"ETag", "Content-Disposition"}
+)
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
+// DefaultCORSMaxAge is how long browsers may cache a preflight response
+const DefaultCORSMaxAge = 10 * time.Minute
+
+// CORSConfig holds cross-origin settings
+type CORSConfig struct {
+	// AllowedOrigins lists origins such as "https://app.example.com".
+	// "https://*.example.com" allows every subdomain of example.com, and "*"
+	// allows any origin when credentials are not allowed.
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	ExposedHeaders []string
+	// AllowCredentials lets browsers send cookies and Authorization headers
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response
+	MaxAge time.Duration
+}
+
+// LoadCORSConfig reads cross-origin settings from the environment
+func LoadCORSConfig() (CORSConfig, error) {
+	config := CORSConfig{
+		AllowedOrigins:   DefaultCORSOrigins,
+		AllowedMethods:   DefaultCORSMethods,
+		AllowedHeaders:   DefaultCORSHeaders,
+		ExposedHeaders:   DefaultCORSExposedHeaders,
+		AllowCredentials: true,
+		MaxAge:           DefaultCORSMaxAge,
+	}
+
+	if raw, ok := os.LookupEnv("CORS_ALLOWED_ORIGINS"); ok {
+		config.AllowedOrigins = splitList(raw)
+	}
+	if raw := os.Getenv("CORS_ALLOWED_METHODS"); raw != "" {
+		config.AllowedMethods = splitList(strings.ToUpper(raw))
+	}
+	if raw := os.Getenv("CORS_ALLOWED_HEADERS"); raw != "" {
+		config.AllowedHeaders = splitList(raw)
+	}
+
+	if raw := os.Getenv("CORS_ALLOW_CREDENTIALS"); raw != "" {
+		allow, err := strconv.ParseBool(raw)
+		if err != nil {
+			return config, fmt.Errorf("CORS_ALLOW_CREDENTIALS must be true or false, got %q", raw)
+		}
+		config.AllowCredentials = allow
+	}
+
+	if raw := os.Getenv("CORS_MAX_AGE"); raw != "" {
+		maxAge, err := time.ParseDuration(raw)
+		if err != nil || maxAge < 0 {
+			return config, fmt.Errorf("CORS_MAX_AGE must be a duration, got %q", raw)
+		}
+		config.MaxAge = maxAge
+	}
+
+	return config, nil
+}
+
+// splitList parses a comma-separated list, dropping empty entries
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// originPattern matches the Origin header against one allowed origin
+type originPattern struct {
+	any    bool
+	exact  string
+	prefix string // scheme and "://" of a wildcard pattern
+	suffix string // "." + parent domain and optional port of a wildcard pattern
+}
+
+func parseOriginPattern(raw string) (originPattern, error) {
+	raw = strings.ToLower(strings.TrimSuffix(raw, "/"))
+	if raw == "*" {
+		return originPattern{any: true}, nil
+	}
+
+	prefix, host, ok := strings.Cut(raw, "://")
+	if !ok || (prefix != "http" && prefix != "https") {
+		return originPattern{}, fmt.Errorf("origin %q must start with http:// or https://", raw)
+	}
+	parent, wildcard := strings.CutPrefix(host, "*.")
+
+	// Check the shape with the wildcard standing in for a subdomain
+	check := host
+	if wildcard {
+		check = "x." + parent
+	}
+	u, err := url.Parse(prefix + "://" + check)
+	if err != nil || u.Hostname() == "" || u.Path != "" || u.RawQuery != "" || u.User != nil || strings.Contains(u.Host, "*") {
+		return originPattern{}, fmt.Errorf("origin %q must be a scheme and host, with an optional port and a leading *. wildcard", raw)
+	}
+
+	if wildcard {
+		return originPattern{prefix: prefix + "://", suffix: "." + parent}, nil
+	}
+	return originPattern{exact: raw}, nil
+}
+
+func (p originPattern) match(origin string) bool {
+	switch {
+	case p.any:
+		return true
+	case p.exact != "":
+		return origin == p.exact
+	}
+
+	if !strings.HasPrefix(origin, p.prefix) || !strings.HasSuffix(origin, p.suffix) {
+		return false
+	}
+	subdomain := strings.TrimSuffix(strings.TrimPrefix(origin, p.prefix), p.suffix)
+	return subdomain != "" && !strings.ContainsAny(subdomain, "/:@?#") && !strings.HasSuffix(subdomain, ".")
+}
+
+// corsPolicy answers cross-origin requests for the allowed origins
+type corsPolicy struct {
+	origins          []originPattern
+	allowCredentials bool
+	methods          string
+	headers          string
+	exposed          string
+	maxAge           string
+}
+
+// CORS returns middleware that allows cross-origin requests from the
+// configured origins. The request's origin is reflected back with
+// Vary: Origin rather than answered with "*", so credentialed requests work.
+// Preflights from other origins get 403.
+func CORS(config CORSConfig) (gin.HandlerFunc, error) {
+	policy := &corsPolicy{
+		allowCredentials: config.AllowCredentials,
+		methods:          strings.Join(config.AllowedMethods, ", "),
+		headers:          strings.Join(config.AllowedHeaders, ", "),
+		exposed:          strings.Join(config.ExposedHeaders, ", "),
+		maxAge:           strconv.Itoa(int(config.MaxAge.Seconds())),
+	}
+
+	for _, raw := range config.AllowedOrigins {
+		pattern, err := parseOriginPattern(raw)
+		if err != nil {
+			return nil, err
+		}
+		if pattern.any && config.AllowCredentials {
+			return nil, fmt.Errorf("origin \"*\" cannot be combined with credentials; list the allowed origins instead")
 		}
+		policy.origins = append(policy.origins, pattern)
+	}
 
+	return policy.handle, nil
+}
+
+func (policy *corsPolicy) allowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	return slices.ContainsFunc(policy.origins, func(p originPattern) bool { return p.match(origin) })
+}
+
+func (policy *corsPolicy) handle(c *gin.Context) {
+	header := c.Writer.Header()
+	header.Add("Vary", "Origin")
+
+	origin := c.GetHeader("Origin")
+	preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+	if origin == "" {
+		c.Next()
+		return
+	}
+	if !policy.allowed(origin) {
+		if preflight {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Origin not allowed"})
+			return
+		}
 		c.Next()
+		return
+	}
+
+	header.Set("Access-Control-Allow-Origin", origin)
+	if policy.allowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if preflight {
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		header.Set("Access-Control-Allow-Methods", policy.methods)
+		header.Set("Access-Control-Allow-Headers", policy.headers)
+		if policy.maxAge != "0" {
+			header.Set("Access-Control-Max-Age", policy.maxAge)
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+		return
+	}
+
+	if policy.exposed != "" {
+		header.Set("Access-Control-Expose-Headers", policy.exposed)
 	}
+	c.Next()
 }
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCORSRouter(t *testing.T, config CORSConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cors, err := CORS(config)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(cors)
+	router.GET("/albums", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func corsRequest(router *gin.Engine, method, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/albums", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", http.MethodPatch)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORS_Origins(t *testing.T) {
+	router := newCORSRouter(t, CORSConfig{
+		AllowedOrigins:   []string{"http://localhost:3000", "https://*.example.com"},
+		AllowCredentials: true,
+	})
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"http://localhost:3000", true},
+		{"https://app.example.com", true},
+		{"https://eu.app.example.com", true},
+		{"HTTPS://App.Example.com", true},
+		{"https://example.com", false},
+		{"http://app.example.com", false},
+		{"https://app.example.com:8443", false},
+		{"https://app.example.com.evil.io", false},
+		{"https://evilexample.com", false},
+		{"http://localhost:3001", false},
+	}
+
+	for _, tt := range tests {
+		w := corsRequest(router, http.MethodGet, tt.origin)
+		assert.Equal(t, http.StatusOK, w.Code, tt.origin)
+		assert.Contains(t, w.Header().Values("Vary"), "Origin", tt.origin)
+		if tt.allowed {
+			assert.Equal(t, tt.origin, w.Header().Get("Access-Control-Allow-Origin"), tt.origin)
+			assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"), tt.origin)
+		} else {
+			assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"), tt.origin)
+		}
+	}
+
+	// Same-origin and non-browser requests carry no Origin and pass through
+	w := corsRequest(router, http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	router := newCORSRouter(t, CORSConfig{
+		AllowedOrigins: []string{"https://*.example.com"},
+		AllowedMethods: DefaultCORSMethods,
+		AllowedHeaders: []string{"Content-Type", "Authorization"},
+		MaxAge:         time.Hour,
+	})
+
+	w := corsRequest(router, http.MethodOptions, "https://app.example.com")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Methods"), http.MethodPatch)
+	assert.Equal(t, "Content-Type, Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "3600", w.Header().Get("Access-Control-Max-Age"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+
+	w = corsRequest(router, http.MethodOptions, "https://evil.io")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORS_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config CORSConfig
+	}{
+		{"any origin with credentials", CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}},
+		{"missing scheme", CORSConfig{AllowedOrigins: []string{"app.example.com"}}},
+		{"path", CORSConfig{AllowedOrigins: []string{"https://app.example.com/ui"}}},
+		{"inner wildcard", CORSConfig{AllowedOrigins: []string{"https://app.*.example.com"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CORS(tt.config)
+			assert.Error(t, err)
+		})
+	}
+
+	// Any origin is fine without credentials
+	router := newCORSRouter(t, CORSConfig{AllowedOrigins: []string{"*"}})
+	w := corsRequest(router, http.MethodGet, "https://anywhere.io")
+	assert.Equal(t, "https://anywhere.io", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestLoadCORSConfig(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://app.example.com, https://*.example.org")
+	t.Setenv("CORS_ALLOWED_METHODS", "get,patch")
+	t.Setenv("CORS_MAX_AGE", "1h")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "false")
+
+	config, err := LoadCORSConfig()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://app.example.com", "https://*.example.org"}, config.AllowedOrigins)
+	assert.Equal(t, []string{"GET", "PATCH"}, config.AllowedMethods)
+	assert.Equal(t, DefaultCORSHeaders, config.AllowedHeaders)
+	assert.Equal(t, time.Hour, config.MaxAge)
+	assert.False(t, config.AllowCredentials)
+
+	t.Setenv("CORS_MAX_AGE", "soon")
+	_, err = LoadCORSConfig()
+	assert.Error(t, err)
+}
@@ -0,0 +1,265 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidToken is returned when a token is malformed, forged or not
+	// meant for this service
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrTokenExpired is returned when a token is past its expiry or not yet valid
+	ErrTokenExpired = errors.New("token expired")
+)
+
+// jwtHeader is the JOSE header of a JWT
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// jwtClaims are the registered and custom claims the service reads
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  audience        `json:"aud"`
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+	Scope     string          `json:"scope"`
+	Scp       json.RawMessage `json:"scp"`
+	Roles     []string        `json:"roles"`
+	Tenant    string          `json:"tenant"`
+}
+
+// audience accepts the "aud" claim as a string or an array of strings
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// scopes merges the space-delimited "scope" claim with "scp", which issuers
+// send as either a string or an array
+func (c jwtClaims) scopes() []string {
+	scopes := strings.Fields(c.Scope)
+	if len(c.Scp) > 0 {
+		var list []string
+		var single string
+		if err := json.Unmarshal(c.Scp, &list); err == nil {
+			scopes = append(scopes, list...)
+		} else if err := json.Unmarshal(c.Scp, &single); err == nil {
+			scopes = append(scopes, strings.Fields(single)...)
+		}
+	}
+	return scopes
+}
+
+// jwtVerifier checks HS256 tokens against a shared secret and RS256 tokens
+// against the RSA keys of a JWKS file. Other algorithms, including "none",
+// are always rejected.
+type jwtVerifier struct {
+	secret   []byte
+	keys     map[string]*rsa.PublicKey
+	issuer   string
+	audience string
+	leeway   time.Duration
+}
+
+// verify checks a token's signature and claims and returns its principal
+func (v *jwtVerifier) verify(token string, now time.Time) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	digest := sha256.Sum256(signed)
+
+	switch header.Alg {
+	case "HS256":
+		if len(v.secret) == 0 {
+			return nil, fmt.Errorf("%w: HS256 tokens are not accepted", ErrInvalidToken)
+		}
+		mac := hmac.New(sha256.New, v.secret)
+		mac.Write(signed)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return nil, fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+	case "RS256":
+		key, err := v.key(header.Kid)
+		if err != nil {
+			return nil, err
+		}
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return nil, fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Alg)
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+	if err := v.checkClaims(claims, now); err != nil {
+		return nil, err
+	}
+
+	return &Principal{
+		Subject: claims.Subject,
+		Scopes:  claims.scopes(),
+		Roles:   claims.Roles,
+		Tenant:  claims.Tenant,
+		Method:  AuthMethodJWT,
+	}, nil
+}
+
+// key picks the RSA key named by kid, or the only key when the token names none
+func (v *jwtVerifier) key(kid string) (*rsa.PublicKey, error) {
+	if len(v.keys) == 0 {
+		return nil, fmt.Errorf("%w: RS256 tokens are not accepted", ErrInvalidToken)
+	}
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, nil
+		}
+	}
+	key, ok := v.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+	}
+	return key, nil
+}
+
+func (v *jwtVerifier) checkClaims(claims jwtClaims, now time.Time) error {
+	if claims.Subject == "" {
+		return fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+	if claims.ExpiresAt == nil {
+		return fmt.Errorf("%w: missing expiry", ErrInvalidToken)
+	}
+	if claims.Tenant != "" && !ValidTenant(claims.Tenant) {
+		return fmt.Errorf("%w: malformed tenant", ErrInvalidToken)
+	}
+	if now.After(time.Unix(*claims.ExpiresAt, 0).Add(v.leeway)) {
+		return ErrTokenExpired
+	}
+	if claims.NotBefore != nil && now.Add(v.leeway).Before(time.Unix(*claims.NotBefore, 0)) {
+		return fmt.Errorf("%w: token is not valid yet", ErrTokenExpired)
+	}
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+	if v.audience != "" {
+		for _, aud := range claims.Audience {
+			if aud == v.audience {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// minRSAKeyBits is the smallest RSA key accepted for RS256
+const minRSAKeyBits = 2048
+
+// jwk is one key of a JSON Web Key Set
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// loadJWKS reads the RSA signing keys of a JWKS file, keyed by kid. Keys of
+// other types or for encryption are skipped.
+func loadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS file: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") || (k.Alg != "" && k.Alg != "RS256") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("JWKS key %q has a malformed modulus", k.Kid)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("JWKS key %q has a malformed exponent", k.Kid)
+		}
+
+		key := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+		if key.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("JWKS key %q is shorter than %d bits", k.Kid, minRSAKeyBits)
+		}
+
+		if _, ok := keys[k.Kid]; ok {
+			return nil, fmt.Errorf("JWKS file has more than one key %q", k.Kid)
+		}
+		keys[k.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS file %s has no RSA signing keys", path)
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Permission is an operation a principal may be allowed to perform
+type Permission string
+
+// Permissions checked by the album and chat domains and by ResolveTenant
+const (
+	PermAlbumsRead    Permission = "albums.read"
+	PermAlbumsCreate  Permission = "albums.create"
+	PermAlbumsUpdate  Permission = "albums.update"
+	PermAlbumsReprice Permission = "albums.reprice"
+	PermAlbumsDelete  Permission = "albums.delete"
+	PermAlbumsPurge   Permission = "albums.purge"
+	PermRatesManage   Permission = "rates.manage"
+	PermChat          Permission = "chat"
+	// PermTenantsSwitch lets a principal not bound to a tenant pick any
+	// tenant with the X-Tenant-ID header
+	PermTenantsSwitch Permission = "tenants.switch"
+)
+
+// Roles
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleIntern = "intern"
+	RoleViewer = "viewer"
+)
+
+// rolePermissions grants permissions to the roles carried in a token's
+// "roles" claim. Unknown roles grant nothing.
+var rolePermissions = map[string][]Permission{
+	RoleAdmin: {
+		PermAlbumsRead, PermAlbumsCreate, PermAlbumsUpdate, PermAlbumsReprice,
+		PermAlbumsDelete, PermAlbumsPurge, PermRatesManage, PermChat, PermTenantsSwitch,
+	},
+	RoleEditor: {
+		PermAlbumsRead, PermAlbumsCreate, PermAlbumsUpdate, PermAlbumsReprice,
+		PermAlbumsDelete, PermChat,
+	},
+	RoleIntern: {PermAlbumsRead, PermAlbumsUpdate, PermChat},
+	RoleViewer: {PermAlbumsRead},
+}
+
+// scopePermissions grants permissions to API keys, which have scopes
+// instead of roles
+var scopePermissions = map[string][]Permission{
+	ScopeAlbumsRead:  {PermAlbumsRead},
+	ScopeAlbumsWrite: {PermAlbumsCreate, PermAlbumsUpdate, PermAlbumsReprice, PermAlbumsDelete},
+	ScopeChat:        {PermChat},
+}
+
+// ErrForbidden is returned when the caller lacks a permission
+var ErrForbidden = errors.New("forbidden")
+
+// PermissionError reports the permission a caller was missing
+type PermissionError struct {
+	Permission Permission
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("missing permission %q", e.Permission)
+}
+
+// Unwrap makes a PermissionError match ErrForbidden
+func (e *PermissionError) Unwrap() error {
+	return ErrForbidden
+}
+
+// Can reports whether the principal holds the permission. Token holders get
+// the permissions of their roles; API keys get those of their scopes.
+func (p *Principal) Can(permission Permission) bool {
+	grants, names := rolePermissions, p.Roles
+	if p.Method == AuthMethodAPIKey {
+		grants, names = scopePermissions, p.Scopes
+	}
+	for _, name := range names {
+		if slices.Contains(grants[name], permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize checks that the principal carried by ctx holds every permission.
+// Anonymous requests, let through when authentication is not required, are
+// not checked.
+func Authorize(ctx context.Context, permissions ...Permission) error {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	for _, permission := range permissions {
+		if !principal.Can(permission) {
+			return &PermissionError{Permission: permission}
+		}
+	}
+	return nil
+}
+
+// RequirePermission is middleware that responds 403 unless the caller holds
+// every permission
+func RequirePermission(permissions ...Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := Authorize(c.Request.Context(), permissions...); err != nil {
+			Forbidden(c, err)
+			return
+		}
+		c.Next()
+	}
+}
+
+// Forbidden aborts the request with 403, naming the missing permission
+func Forbidden(c *gin.Context, err error) {
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": ForbiddenMessage(err)})
+}
+
+// ForbiddenMessage describes an authorization failure to the caller
+func ForbiddenMessage(err error) string {
+	var permErr *PermissionError
+	if errors.As(err, &permErr) {
+		return fmt.Sprintf("Missing required permission %q", permErr.Permission)
+	}
+	return "Forbidden"
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultTenant owns every album created before tenants existed, and is used
+// when a request names no tenant
+const DefaultTenant = "default"
+
+// TenantHeader selects the tenant of a request whose principal may switch
+// tenants
+const TenantHeader = "X-Tenant-ID"
+
+// tenantPattern is the shape of a tenant ID: a lowercase slug of at most 63
+// characters
+var tenantPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// ValidTenant reports whether id is a well-formed tenant ID
+func ValidTenant(id string) bool {
+	return tenantPattern.MatchString(id)
+}
+
+type tenantContextKey struct{}
+
+// WithTenant returns a context scoped to the tenant
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// TenantFrom returns the tenant ctx is scoped to, or DefaultTenant when it
+// names none, as for background jobs and single-tenant deployments
+func TenantFrom(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantContextKey{}).(string); ok && tenant != "" {
+		return tenant
+	}
+	return DefaultTenant
+}
+
+// ResolveTenant is middleware that scopes the request to a tenant. A
+// principal bound to a tenant always uses it. Other principals use
+// DefaultTenant, and may only pick another with the X-Tenant-ID header if
+// they hold PermTenantsSwitch; anonymous requests, which are only let
+// through when authentication is off, may pick any. A header naming a
+// tenant the caller cannot use gets 403. It must run after Authenticate.
+func ResolveTenant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenant := strings.TrimSpace(c.GetHeader(TenantHeader))
+		if tenant != "" && !ValidTenant(tenant) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid tenant ID"})
+			return
+		}
+
+		if principal, ok := PrincipalFrom(c); ok {
+			own := principal.Tenant
+			if own == "" && !principal.Can(PermTenantsSwitch) {
+				own = DefaultTenant
+			}
+			if own != "" {
+				if tenant != "" && tenant != own {
+					c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+						"error": fmt.Sprintf("Not allowed to access tenant %q", tenant),
+					})
+					return
+				}
+				tenant = own
+			}
+		}
+		if tenant == "" {
+			tenant = DefaultTenant
+		}
+
+		c.Request = c.Request.WithContext(WithTenant(c.Request.Context(), tenant))
+		c.Next()
+	}
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantFrom(t *testing.T) {
+	assert.Equal(t, DefaultTenant, TenantFrom(context.Background()))
+	assert.Equal(t, "acme", TenantFrom(WithTenant(context.Background(), "acme")))
+
+	assert.True(t, ValidTenant("acme-eu_1"))
+	assert.False(t, ValidTenant(""))
+	assert.False(t, ValidTenant("Acme"))
+	assert.False(t, ValidTenant("-acme"))
+}
+
+func TestResolveTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		switch tenant := c.GetHeader("X-Principal-Tenant"); tenant {
+		case "":
+		case "unbound":
+			SetPrincipal(c, &Principal{Subject: "sam", Roles: []string{RoleEditor}})
+		case "admin":
+			SetPrincipal(c, &Principal{Subject: "root", Roles: []string{RoleAdmin}})
+		case "unbound key":
+			SetPrincipal(c, &Principal{Subject: "api-key:1", Scopes: Scopes, Method: AuthMethodAPIKey})
+		default:
+			SetPrincipal(c, &Principal{Subject: "sam", Tenant: tenant})
+		}
+	}, ResolveTenant())
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, TenantFrom(c.Request.Context()))
+	})
+
+	tests := []struct {
+		name      string
+		principal string
+		header    string
+		want      int
+		tenant    string
+	}{
+		{"no tenant", "", "", http.StatusOK, DefaultTenant},
+		{"header", "", "acme", http.StatusOK, "acme"},
+		{"malformed header", "", "ACME!", http.StatusBadRequest, ""},
+		{"bound principal", "acme", "", http.StatusOK, "acme"},
+		{"bound principal, same header", "acme", "acme", http.StatusOK, "acme"},
+		{"bound principal, other header", "acme", "globex", http.StatusForbidden, ""},
+		{"unbound principal", "unbound", "", http.StatusOK, DefaultTenant},
+		{"unbound principal, default header", "unbound", DefaultTenant, http.StatusOK, DefaultTenant},
+		{"unbound principal, other header", "unbound", "acme", http.StatusForbidden, ""},
+		{"admin", "admin", "acme", http.StatusOK, "acme"},
+		{"unbound API key", "unbound key", "", http.StatusOK, DefaultTenant},
+		{"unbound API key, other header", "unbound key", "acme", http.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.principal != "" {
+				req.Header.Set("X-Principal-Tenant", tt.principal)
+			}
+			if tt.header != "" {
+				req.Header.Set(TenantHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want, w.Code)
+			if tt.tenant != "" {
+				assert.Equal(t, tt.tenant, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthenticate_TenantClaim(t *testing.T) {
+	authenticator, err := NewAuthenticator(AuthConfig{Secret: []byte(testSecret)})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/", authenticator.Authenticate(), ResolveTenant(), func(c *gin.Context) {
+		c.String(http.StatusOK, TenantFrom(c.Request.Context()))
+	})
+
+	w := get(router, signHS256(t, testSecret, claims(map[string]interface{}{"tenant": "acme"})))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "acme", w.Body.String())
+
+	w = get(router, signHS256(t, testSecret, claims(map[string]interface{}{"tenant": "Not A Tenant"})))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+var (
+	// ErrChecksumMismatch is returned when an applied migration's SQL has been modified
+	ErrChecksumMismatch = errors.New("applied migrations have been modified")
+)
+
+// checksum hashes a migration's up SQL. Whitespace is collapsed first so
+// re-indenting or reformatting a migration does not count as a change.
+func checksum(m migration) string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(m.up), " ")))
+	return hex.EncodeToString(sum[:])
+}
+
+// drift describes an applied migration whose SQL no longer matches
+type drift struct {
+	version     int
+	description string
+	applied     string
+	current     string
+}
+
+func (d drift) String() string {
+	return fmt.Sprintf("version %d (%s): applied checksum %s, current checksum %s",
+		d.version, d.description, shortChecksum(d.applied), shortChecksum(d.current))
+}
+
+// findDrift compares recorded checksums with the current migrations.
+// Records without a checksum predate checksum tracking and are skipped.
+func findDrift(ms []migration, records map[int]appliedMigration) []drift {
+	var drifted []drift
+
+	for _, m := range ms {
+		record, ok := records[m.version]
+		if !ok || record.checksum == "" {
+			continue
+		}
+		if current := checksum(m); record.checksum != current {
+			drifted = append(drifted, drift{
+				version:     m.version,
+				description: m.description,
+				applied:     record.checksum,
+				current:     current,
+			})
+		}
+	}
+
+	return drifted
+}
+
+// backfillChecksums records checksums for migrations applied before they were tracked
+func (d *Database) backfillChecksums(ctx context.Context, records map[int]appliedMigration) error {
+	for _, m := range migrations {
+		record, ok := records[m.version]
+		if !ok || record.checksum != "" {
+			continue
+		}
+
+		sum := checksum(m)
+		if _, err := d.Pool.Exec(ctx, "UPDATE schema_migrations SET checksum = $1 WHERE version = $2", sum, m.version); err != nil {
+			return fmt.Errorf("failed to record checksum for migration %d: %w", m.version, err)
+		}
+
+		record.checksum = sum
+		records[m.version] = record
+		log.Printf("Recorded checksum for previously applied migration %d", m.version)
+	}
+
+	return nil
+}
+
+// RepairChecksums accepts the current SQL of applied migrations as correct by
+// overwriting their recorded checksums. With no versions, every drifted
+// migration is repaired. Use it only after an intentional edit.
+func (d *Database) RepairChecksums(ctx context.Context, versions ...int) error {
+	return d.withMigrationLock(ctx, func() error {
+		if err := d.ensureTrackingTable(ctx); err != nil {
+			return err
+		}
+
+		records, err := d.readAppliedMigrations(ctx)
+		if err != nil {
+			return err
+		}
+
+		if len(versions) == 0 {
+			for _, drifted := range findDrift(migrations, records) {
+				versions = append(versions, drifted.version)
+			}
+		}
+
+		if len(versions) == 0 {
+			log.Println("No drifted migrations to repair")
+			return nil
+		}
+
+		for _, version := range versions {
+			m := findMigration(migrations, version)
+			if m == nil {
+				return fmt.Errorf("unknown migration version %d", version)
+			}
+			if _, ok := records[version]; !ok {
+				return fmt.Errorf("migration %d is not applied", version)
+			}
+
+			if _, err := d.Pool.Exec(ctx, "UPDATE schema_migrations SET checksum = $1 WHERE version = $2", checksum(*m), version); err != nil {
+				return fmt.Errorf("failed to repair checksum for migration %d: %w", version, err)
+			}
+			log.Printf("✓ Repaired checksum for migration %d: %s", version, m.description)
+		}
+
+		return nil
+	})
+}
+
+// shortChecksum abbreviates a checksum for display
+func shortChecksum(sum string) string {
+	if len(sum) > 12 {
+		return sum[:12]
+	}
+	return sum
+}
@@ -0,0 +1,481 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// migrationLockKey identifies the advisory lock held while migrating.
+	// Every instance of the service uses the same key.
+	migrationLockKey int64 = 0x616c62756d73 // "albums"
+
+	// DefaultMigrationLockTimeout is how long to wait for another instance's migration
+	DefaultMigrationLockTimeout = 2 * time.Minute
+
+	// migrationLockPollInterval is how often the lock is retried while waiting
+	migrationLockPollInterval = 500 * time.Millisecond
+)
+
+var (
+	// ErrMigrationLockTimeout is returned when another instance holds the migration lock for too long
+	ErrMigrationLockTimeout = errors.New("timed out waiting for migration lock")
+	// ErrMigrationState is returned when applied migrations do not line up with the known migrations
+	ErrMigrationState = errors.New("inconsistent migration state")
+)
+
+// MigrationStatus describes whether a single migration has been applied
+type MigrationStatus struct {
+	Version     int
+	Description string
+	Applied     bool
+	AppliedAt   *time.Time
+	// Drifted is set when the migration's SQL changed after it was applied
+	Drifted bool
+}
+
+// MigrationReport is the result of inspecting the migration state
+type MigrationReport struct {
+	CurrentVersion int
+	Migrations     []MigrationStatus
+	// Problems lists gaps, out-of-order and unknown versions, and drifted checksums
+	Problems []string
+}
+
+// Migrate applies all pending migrations.
+// Concurrent callers (e.g. several replicas booting at once) take turns.
+func (d *Database) Migrate(ctx context.Context) error {
+	return d.MigrateTo(ctx, latestVersion(migrations))
+}
+
+// MigrateDown rolls back the last n applied migrations
+func (d *Database) MigrateDown(ctx context.Context, n int) error {
+	if n <= 0 {
+		return fmt.Errorf("number of migrations to roll back must be positive, got %d", n)
+	}
+
+	return d.withMigrationLock(ctx, func() error {
+		applied, err := d.checkedAppliedMigrations(ctx)
+		if err != nil {
+			return err
+		}
+
+		versions := appliedVersions(applied)
+		if n > len(versions) {
+			return fmt.Errorf("cannot roll back %d migration(s), only %d applied", n, len(versions))
+		}
+
+		target := 0
+		if n < len(versions) {
+			target = versions[len(versions)-n-1]
+		}
+
+		return d.migrateTo(ctx, target)
+	})
+}
+
+// MigrateTo applies or rolls back migrations until the database is at the given version.
+// Version 0 rolls back every migration.
+func (d *Database) MigrateTo(ctx context.Context, target int) error {
+	return d.withMigrationLock(ctx, func() error {
+		return d.migrateTo(ctx, target)
+	})
+}
+
+// migrateTo implements MigrateTo; the caller must hold the migration lock
+func (d *Database) migrateTo(ctx context.Context, target int) error {
+	if err := validateMigrations(migrations); err != nil {
+		return err
+	}
+	if target != 0 && findMigration(migrations, target) == nil {
+		return fmt.Errorf("unknown migration version %d", target)
+	}
+
+	applied, err := d.checkedAppliedMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	currentVersion := maxVersion(applied)
+	log.Printf("Current database version: %d", currentVersion)
+
+	migrationsRan := 0
+
+	// Roll back newest first
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.version <= target || !applied[m.version] {
+			continue
+		}
+		if err := d.applyDown(ctx, m); err != nil {
+			return err
+		}
+		migrationsRan++
+	}
+
+	// Apply oldest first
+	for _, m := range migrations {
+		if m.version > target || applied[m.version] {
+			continue
+		}
+		if err := d.applyUp(ctx, m); err != nil {
+			return err
+		}
+		migrationsRan++
+	}
+
+	if migrationsRan == 0 {
+		log.Println("Database schema is up to date")
+	} else {
+		log.Printf("Successfully ran %d migration(s), database version: %d", migrationsRan, target)
+	}
+
+	return nil
+}
+
+// Redo rolls back the most recent migration and applies it again
+func (d *Database) Redo(ctx context.Context) error {
+	return d.withMigrationLock(ctx, func() error {
+		applied, err := d.checkedAppliedMigrations(ctx)
+		if err != nil {
+			return err
+		}
+
+		current := maxVersion(applied)
+		if current == 0 {
+			return errors.New("no migrations applied, nothing to redo")
+		}
+
+		m := findMigration(migrations, current)
+		if err := d.applyDown(ctx, *m); err != nil {
+			return err
+		}
+
+		return d.applyUp(ctx, *m)
+	})
+}
+
+// withMigrationLock runs fn while holding a Postgres advisory lock, so only
+// one instance migrates at a time. Waiting instances give up after
+// MigrationLockTimeout; once they get the lock, fn re-reads the applied
+// versions and usually finds nothing left to do.
+func (d *Database) withMigrationLock(ctx context.Context, fn func() error) error {
+	// Advisory locks belong to a session, so hold one connection throughout
+	conn, err := d.Pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection for migration lock: %w", err)
+	}
+	defer conn.Release()
+
+	timeout := d.MigrationLockTimeout
+	if timeout <= 0 {
+		timeout = DefaultMigrationLockTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	for waiting := false; ; waiting = true {
+		var acquired bool
+		if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", migrationLockKey).Scan(&acquired); err != nil {
+			return fmt.Errorf("failed to take migration lock: %w", err)
+		}
+		if acquired {
+			break
+		}
+
+		if !waiting {
+			log.Println("Another instance is migrating the database, waiting...")
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w after %s", ErrMigrationLockTimeout, timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(migrationLockPollInterval):
+		}
+	}
+
+	defer func() {
+		// Unlock even if ctx was cancelled; a failed unlock is released when the session ends
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey); err != nil {
+			log.Printf("Warning: failed to release migration lock: %v", err)
+		}
+	}()
+
+	return fn()
+}
+
+// MigrationStatus reports which migrations are applied and any inconsistencies
+func (d *Database) MigrationStatus(ctx context.Context) (*MigrationReport, error) {
+	if err := d.ensureTrackingTable(ctx); err != nil {
+		return nil, err
+	}
+
+	records, err := d.readAppliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := appliedSet(records)
+	drifted := findDrift(migrations, records)
+
+	report := &MigrationReport{
+		CurrentVersion: maxVersion(applied),
+		Problems:       findProblems(migrations, applied),
+	}
+	if err := validateMigrations(migrations); err != nil {
+		report.Problems = append([]string{err.Error()}, report.Problems...)
+	}
+	for _, entry := range drifted {
+		report.Problems = append(report.Problems, entry.String())
+	}
+
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.version, Description: m.description}
+		if record, ok := records[m.version]; ok {
+			at := record.appliedAt
+			status.Applied = true
+			status.AppliedAt = &at
+		}
+		for _, entry := range drifted {
+			if entry.version == m.version {
+				status.Drifted = true
+			}
+		}
+		report.Migrations = append(report.Migrations, status)
+	}
+
+	return report, nil
+}
+
+// ensureTrackingTable creates the migrations tracking table if it doesn't exist
+func (d *Database) ensureTrackingTable(ctx context.Context) error {
+	createTrackingTable := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT PRIMARY KEY,
+			description TEXT NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+	`
+	if _, err := d.Pool.Exec(ctx, createTrackingTable); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	// Tables created before checksums were tracked lack the column
+	if _, err := d.Pool.Exec(ctx, "ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS checksum TEXT"); err != nil {
+		return fmt.Errorf("failed to add checksum to schema_migrations table: %w", err)
+	}
+
+	return nil
+}
+
+// appliedMigration is a row of schema_migrations
+type appliedMigration struct {
+	appliedAt time.Time
+	// checksum is empty for migrations applied before checksums were tracked
+	checksum string
+}
+
+// readAppliedMigrations returns the schema_migrations rows keyed by version
+func (d *Database) readAppliedMigrations(ctx context.Context) (map[int]appliedMigration, error) {
+	rows, err := d.Pool.Query(ctx, "SELECT version, applied_at, COALESCE(checksum, '') FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	records := make(map[int]appliedMigration)
+	for rows.Next() {
+		var version int
+		var record appliedMigration
+		if err := rows.Scan(&version, &record.appliedAt, &record.checksum); err != nil {
+			return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+		records[version] = record
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	return records, nil
+}
+
+// checkedAppliedMigrations returns the applied versions, refusing to continue
+// when they do not line up with the known migrations or their SQL has changed
+// since they were applied. The caller must hold the migration lock.
+func (d *Database) checkedAppliedMigrations(ctx context.Context) (map[int]bool, error) {
+	if err := d.ensureTrackingTable(ctx); err != nil {
+		return nil, err
+	}
+
+	records, err := d.readAppliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := appliedSet(records)
+	if problems := findProblems(migrations, applied); len(problems) > 0 {
+		return nil, fmt.Errorf("%w:\n  %s", ErrMigrationState, strings.Join(problems, "\n  "))
+	}
+
+	if err := d.backfillChecksums(ctx, records); err != nil {
+		return nil, err
+	}
+
+	if drifted := findDrift(migrations, records); len(drifted) > 0 {
+		lines := make([]string, len(drifted))
+		for i, entry := range drifted {
+			lines[i] = entry.String()
+		}
+		return nil, fmt.Errorf("%w:\n  %s\nrun \"migrate repair\" if these changes are intentional",
+			ErrChecksumMismatch, strings.Join(lines, "\n  "))
+	}
+
+	return applied, nil
+}
+
+// applyUp runs a migration and records it, in a single transaction
+func (d *Database) applyUp(ctx context.Context, m migration) error {
+	log.Printf("Running migration %d: %s", m.version, m.description)
+
+	// Start transaction for this migration
+	tx, err := d.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for migration %d: %w", m.version, err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Run the migration
+	if _, err := tx.Exec(ctx, m.up); err != nil {
+		return fmt.Errorf("migration %d failed: %w", m.version, err)
+	}
+
+	// Record that this migration was applied
+	if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version, description, checksum) VALUES ($1, $2, $3)", m.version, m.description, checksum(m)); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+	}
+
+	// Commit transaction
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", m.version, err)
+	}
+
+	log.Printf("✓ Migration %d completed: %s", m.version, m.description)
+	return nil
+}
+
+// applyDown reverses a migration and removes its record, in a single transaction
+func (d *Database) applyDown(ctx context.Context, m migration) error {
+	if strings.TrimSpace(m.down) == "" {
+		return fmt.Errorf("migration %d has no down SQL and cannot be rolled back", m.version)
+	}
+
+	log.Printf("Rolling back migration %d: %s", m.version, m.description)
+
+	tx, err := d.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for rollback of migration %d: %w", m.version, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, m.down); err != nil {
+		return fmt.Errorf("rollback of migration %d failed: %w", m.version, err)
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM schema_migrations WHERE version = $1", m.version); err != nil {
+		return fmt.Errorf("failed to remove record of migration %d: %w", m.version, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit rollback of migration %d: %w", m.version, err)
+	}
+
+	log.Printf("✓ Migration %d rolled back: %s", m.version, m.description)
+	return nil
+}
+
+// validateMigrations checks that versions start at 1 and increase without gaps
+func validateMigrations(ms []migration) error {
+	for i, m := range ms {
+		if m.version != i+1 {
+			return fmt.Errorf("%w: migration at position %d has version %d, expected %d", ErrMigrationState, i+1, m.version, i+1)
+		}
+	}
+	return nil
+}
+
+// findProblems compares applied versions with the known migrations. It reports
+// applied versions with no migration, and pending migrations older than the
+// newest applied one (a gap that a plain "apply everything newer" would skip).
+func findProblems(ms []migration, applied map[int]bool) []string {
+	var problems []string
+
+	for _, version := range appliedVersions(applied) {
+		if findMigration(ms, version) == nil {
+			problems = append(problems, fmt.Sprintf("version %d is applied but no such migration exists", version))
+		}
+	}
+
+	current := maxVersion(applied)
+	for _, m := range ms {
+		if m.version < current && !applied[m.version] {
+			problems = append(problems, fmt.Sprintf("version %d is pending but newer version %d is already applied", m.version, current))
+		}
+	}
+
+	return problems
+}
+
+// findMigration returns the migration with the given version, or nil
+func findMigration(ms []migration, version int) *migration {
+	for i := range ms {
+		if ms[i].version == version {
+			return &ms[i]
+		}
+	}
+	return nil
+}
+
+// appliedSet returns the set of applied versions
+func appliedSet(records map[int]appliedMigration) map[int]bool {
+	applied := make(map[int]bool, len(records))
+	for version := range records {
+		applied[version] = true
+	}
+	return applied
+}
+
+// appliedVersions returns the applied versions in ascending order
+func appliedVersions(applied map[int]bool) []int {
+	versions := make([]int, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+// maxVersion returns the newest applied version, or 0 if none
+func maxVersion(applied map[int]bool) int {
+	current := 0
+	for version := range applied {
+		if version > current {
+			current = version
+		}
+	}
+	return current
+}
+
+// latestVersion returns the newest known migration version
+func latestVersion(ms []migration) int {
+	if len(ms) == 0 {
+		return 0
+	}
+	return ms[len(ms)-1].version
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrations_AreValid(t *testing.T) {
+	assert.NoError(t, validateMigrations(migrations))
+
+	for _, m := range migrations {
+		assert.NotEmpty(t, m.up, "migration %d has no up SQL", m.version)
+		assert.NotEmpty(t, m.down, "migration %d has no down SQL", m.version)
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0002_add_genre_to_albums.up.sql":   {Data: []byte("ALTER TABLE albums ADD COLUMN genre TEXT;")},
+		"m/0001_create_albums_table.up.sql":   {Data: []byte("CREATE TABLE albums (id INT);")},
+		"m/0001_create_albums_table.down.sql": {Data: []byte("DROP TABLE albums;")},
+	}
+
+	ms, err := loadMigrations(fsys, "m")
+	assert.NoError(t, err)
+	assert.Equal(t, []migration{
+		{version: 1, description: "Create albums table", up: "CREATE TABLE albums (id INT);", down: "DROP TABLE albums;"},
+		{version: 2, description: "Add genre to albums", up: "ALTER TABLE albums ADD COLUMN genre TEXT;"},
+	}, ms)
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	up := &fstest.MapFile{Data: []byte("SELECT 1;")}
+
+	tests := map[string]fstest.MapFS{
+		"malformed name":    {"m/create_albums.up.sql": up},
+		"unknown direction": {"m/0001_create_albums.sideways.sql": up},
+		"duplicate version": {"m/0001_create_albums.up.sql": up, "m/0001_create_artists.up.sql": up},
+		"duplicate file":    {"m/0001_create_albums.up.sql": up, "m/001_create_albums.up.sql": up},
+		"missing up":        {"m/0001_create_albums.down.sql": up},
+		"empty file":        {"m/0001_create_albums.up.sql": {Data: []byte("  \n")}},
+	}
+
+	for name, fsys := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := loadMigrations(fsys, "m")
+			assert.ErrorIs(t, err, ErrInvalidMigrationFile)
+		})
+	}
+
+	// Gaps are caught by the same check Migrate uses
+	_, err := loadMigrations(fstest.MapFS{"m/0002_create_albums.up.sql": up}, "m")
+	assert.ErrorIs(t, err, ErrMigrationState)
+}
+
+func TestValidateMigrations(t *testing.T) {
+	assert.ErrorIs(t, validateMigrations([]migration{{version: 1}, {version: 3}}), ErrMigrationState)
+	assert.ErrorIs(t, validateMigrations([]migration{{version: 2}, {version: 1}}), ErrMigrationState)
+	assert.ErrorIs(t, validateMigrations([]migration{{version: 1}, {version: 1}}), ErrMigrationState)
+}
+
+func TestFindProblems(t *testing.T) {
+	ms := []migration{{version: 1}, {version: 2}, {version: 3}}
+
+	// Nothing applied, or applied in order, is fine
+	assert.Empty(t, findProblems(ms, map[int]bool{}))
+	assert.Empty(t, findProblems(ms, map[int]bool{1: true, 2: true}))
+
+	// A pending migration older than the newest applied one is a gap
+	problems := findProblems(ms, map[int]bool{1: true, 3: true})
+	assert.Equal(t, []string{"version 2 is pending but newer version 3 is already applied"}, problems)
+
+	// An applied version the code does not know about
+	problems = findProblems(ms, map[int]bool{1: true, 2: true, 3: true, 4: true})
+	assert.Equal(t, []string{"version 4 is applied but no such migration exists"}, problems)
+}
+
+func TestChecksum_IgnoresWhitespace(t *testing.T) {
+	a := migration{version: 1, up: "CREATE TABLE t (\n\tid INT\n);"}
+	b := migration{version: 1, up: "  CREATE TABLE t ( id INT );  "}
+	c := migration{version: 1, up: "CREATE TABLE t (id BIGINT);"}
+
+	assert.Equal(t, checksum(a), checksum(b))
+	assert.NotEqual(t, checksum(a), checksum(c))
+}
+
+func TestFindDrift(t *testing.T) {
+	ms := []migration{
+		{version: 1, description: "one", up: "CREATE TABLE one (id INT);"},
+		{version: 2, description: "two", up: "CREATE TABLE two (id INT);"},
+		{version: 3, description: "three", up: "CREATE TABLE three (id INT);"},
+	}
+
+	records := map[int]appliedMigration{
+		1: {checksum: checksum(ms[0])},
+		2: {checksum: checksum(migration{up: "CREATE TABLE two (id BIGINT);"})},
+		// Applied before checksums were tracked
+		3: {},
+	}
+
+	drifted := findDrift(ms, records)
+	if assert.Len(t, drifted, 1) {
+		assert.Equal(t, 2, drifted[0].version)
+		assert.Contains(t, drifted[0].String(), "version 2 (two)")
+	}
+}
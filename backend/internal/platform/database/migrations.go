@@ -0,0 +1,136 @@
+package database
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// migrationFiles holds the SQL migrations shipped with the binary
+//
+// To add a new migration, add a pair of files to the migrations directory:
+//
+//	migrations/0003_add_genre_to_albums.up.sql
+//	migrations/0003_add_genre_to_albums.down.sql
+//
+// The version is the numeric prefix and the description is the rest of the
+// name with underscores as spaces ("Add genre to albums"). The down file is
+// optional, but without it the migration cannot be rolled back.
+//
+// Important rules:
+//   - NEVER modify existing migrations (breaks version tracking). Applied
+//     migrations are checksummed and Migrate refuses to run if one changes;
+//     use "migrate repair" after an intentional fix.
+//   - ALWAYS increment version sequentially (1, 2, 3, not 1, 3, 5)
+//   - Each migration runs in a transaction (auto-rollback on error)
+//   - Applied migrations are tracked in 'schema_migrations' table
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// ErrInvalidMigrationFile is returned when the migrations directory holds a file
+// that cannot be loaded
+var ErrInvalidMigrationFile = errors.New("invalid migration file")
+
+// migrationFileName matches NNNN_description.up.sql and NNNN_description.down.sql
+var migrationFileName = regexp.MustCompile(`^(\d+)_([a-z0-9][a-z0-9_]*)\.(up|down)\.sql$`)
+
+// migration represents a single database migration
+type migration struct {
+	version     int
+	description string
+	up          string
+	down        string
+}
+
+// migrations contains all database migrations in order. A broken migrations
+// directory stops the process at startup rather than at the first migrate call.
+var migrations = mustLoadMigrations(migrationFiles, "migrations")
+
+func mustLoadMigrations(fsys fs.FS, dir string) []migration {
+	ms, err := loadMigrations(fsys, dir)
+	if err != nil {
+		panic(fmt.Sprintf("failed to load migrations: %v", err))
+	}
+	return ms
+}
+
+// loadMigrations reads the migration files in dir, sorted by version
+func loadMigrations(fsys fs.FS, dir string) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		name := entry.Name()
+		match := migrationFileName.FindStringSubmatch(name)
+		if match == nil {
+			return nil, fmt.Errorf("%w: %s does not match NNNN_description.(up|down).sql", ErrInvalidMigrationFile, name)
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("%w: %s has invalid version %q", ErrInvalidMigrationFile, name, match[1])
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		sql := string(data)
+		if strings.TrimSpace(sql) == "" {
+			return nil, fmt.Errorf("%w: %s is empty", ErrInvalidMigrationFile, name)
+		}
+
+		description := describeMigration(match[2])
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version, description: description}
+			byVersion[version] = m
+		} else if m.description != description {
+			return nil, fmt.Errorf("%w: version %d is used by both %q and %q", ErrInvalidMigrationFile, version, m.description, description)
+		}
+
+		target := &m.up
+		if match[3] == "down" {
+			target = &m.down
+		}
+		if *target != "" {
+			return nil, fmt.Errorf("%w: duplicate %s migration for version %d", ErrInvalidMigrationFile, match[3], version)
+		}
+		*target = sql
+	}
+
+	ms := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("%w: migration %d has no up file", ErrInvalidMigrationFile, m.version)
+		}
+		ms = append(ms, *m)
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].version < ms[j].version })
+
+	if err := validateMigrations(ms); err != nil {
+		return nil, err
+	}
+
+	return ms, nil
+}
+
+// describeMigration turns "create_albums_table" into "Create albums table"
+func describeMigration(name string) string {
+	description := strings.ReplaceAll(name, "_", " ")
+	return strings.ToUpper(description[:1]) + description[1:]
+}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS exchange_rates;
+DROP TABLE IF EXISTS album_prices;
+ALTER TABLE albums DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,14 @@
+-- album_id has no foreign key so that history outlives hard deletes
+CREATE TABLE IF NOT EXISTS album_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    album_id INT NOT NULL,
+    action VARCHAR(16) NOT NULL CHECK (action IN ('create', 'update', 'delete', 'restore', 'revert')),
+    before JSONB,
+    after JSONB,
+    actor VARCHAR(255) NOT NULL,
+    source VARCHAR(16) NOT NULL,
+    reverted_from BIGINT REFERENCES album_revisions(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_album_revisions_album_id ON album_revisions(album_id, id DESC);
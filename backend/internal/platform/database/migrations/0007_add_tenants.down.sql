@@ -0,0 +1,19 @@
+DROP POLICY IF EXISTS album_prices_tenant_isolation ON album_prices;
+ALTER TABLE album_prices NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE album_prices DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS album_revisions_tenant_isolation ON album_revisions;
+ALTER TABLE album_revisions NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE album_revisions DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS albums_tenant_isolation ON albums;
+ALTER TABLE albums NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE albums DISABLE ROW LEVEL SECURITY;
+
+DROP INDEX IF EXISTS idx_album_revisions_tenant_album;
+CREATE INDEX IF NOT EXISTS idx_album_revisions_album_id ON album_revisions(album_id, id DESC);
+DROP INDEX IF EXISTS idx_albums_tenant_id;
+
+ALTER TABLE api_keys DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE album_revisions DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE albums DROP COLUMN IF EXISTS tenant_id;
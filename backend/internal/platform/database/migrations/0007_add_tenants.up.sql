@@ -0,0 +1,31 @@
+-- Existing albums, history and keys belong to the default tenant
+ALTER TABLE albums ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE album_revisions ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+-- A NULL tenant binds the key to the default tenant
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63);
+
+CREATE INDEX IF NOT EXISTS idx_albums_tenant_id ON albums(tenant_id, id);
+DROP INDEX IF EXISTS idx_album_revisions_album_id;
+CREATE INDEX IF NOT EXISTS idx_album_revisions_tenant_album ON album_revisions(tenant_id, album_id, id DESC);
+
+-- Row-level security confines every statement to the tenant the service
+-- sets in app.tenant_id for its transaction. FORCE applies it to the table
+-- owner too; superusers and roles with BYPASSRLS are still exempt.
+-- app.all_tenants lets the retention job read and delete across tenants.
+ALTER TABLE albums ENABLE ROW LEVEL SECURITY;
+ALTER TABLE albums FORCE ROW LEVEL SECURITY;
+CREATE POLICY albums_tenant_isolation ON albums
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.all_tenants', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+ALTER TABLE album_revisions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE album_revisions FORCE ROW LEVEL SECURITY;
+CREATE POLICY album_revisions_tenant_isolation ON album_revisions
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+-- Price points follow their album, whose own policy applies in the subquery
+ALTER TABLE album_prices ENABLE ROW LEVEL SECURITY;
+ALTER TABLE album_prices FORCE ROW LEVEL SECURITY;
+CREATE POLICY album_prices_tenant_isolation ON album_prices
+    USING (EXISTS (SELECT 1 FROM albums WHERE albums.id = album_prices.album_id));
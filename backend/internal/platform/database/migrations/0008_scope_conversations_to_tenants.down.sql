@@ -0,0 +1,13 @@
+DROP POLICY IF EXISTS conversation_messages_tenant_isolation ON conversation_messages;
+ALTER TABLE conversation_messages NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE conversation_messages DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS conversations_tenant_isolation ON conversations;
+ALTER TABLE conversations NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE conversations DISABLE ROW LEVEL SECURITY;
+
+DROP INDEX IF EXISTS idx_conversations_tenant_owner;
+CREATE INDEX IF NOT EXISTS idx_conversations_deleted_at ON conversations(deleted_at);
+
+ALTER TABLE conversations DROP COLUMN IF EXISTS owner;
+ALTER TABLE conversations DROP COLUMN IF EXISTS tenant_id;
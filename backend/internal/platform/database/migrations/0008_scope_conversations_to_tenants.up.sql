@@ -0,0 +1,20 @@
+-- Existing conversations belong to the default tenant and to no one in
+-- particular; new ones record the subject of the principal that started them
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS owner VARCHAR(255);
+
+DROP INDEX IF EXISTS idx_conversations_deleted_at;
+CREATE INDEX IF NOT EXISTS idx_conversations_tenant_owner ON conversations(tenant_id, owner, updated_at DESC);
+
+-- The same isolation as the album tables, see 0007_add_tenants
+ALTER TABLE conversations ENABLE ROW LEVEL SECURITY;
+ALTER TABLE conversations FORCE ROW LEVEL SECURITY;
+CREATE POLICY conversations_tenant_isolation ON conversations
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+-- Messages follow their conversation, whose own policy applies in the subquery
+ALTER TABLE conversation_messages ENABLE ROW LEVEL SECURITY;
+ALTER TABLE conversation_messages FORCE ROW LEVEL SECURITY;
+CREATE POLICY conversation_messages_tenant_isolation ON conversation_messages
+    USING (EXISTS (SELECT 1 FROM conversations WHERE conversations.id = conversation_messages.conversation_id));
@@ -0,0 +1,9 @@
+-- Nonces of confirmed chat action tokens, so each token runs once across
+-- restarts and replicas. Rows are only needed until the token expires.
+CREATE TABLE IF NOT EXISTS chat_action_redemptions (
+    nonce VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL,
+    redeemed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_action_redemptions_expires_at ON chat_action_redemptions(expires_at);
@@ -0,0 +1,4 @@
+DELETE FROM album_revisions WHERE action = 'purge';
+ALTER TABLE album_revisions DROP CONSTRAINT IF EXISTS album_revisions_action_check;
+ALTER TABLE album_revisions ADD CONSTRAINT album_revisions_action_check
+    CHECK (action IN ('create', 'update', 'delete', 'restore', 'revert'));
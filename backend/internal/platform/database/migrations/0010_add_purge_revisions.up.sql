@@ -0,0 +1,5 @@
+-- Permanent deletions are recorded too, so an album's history shows who
+-- purged it. The purge revision keeps the album's last state in before.
+ALTER TABLE album_revisions DROP CONSTRAINT IF EXISTS album_revisions_action_check;
+ALTER TABLE album_revisions ADD CONSTRAINT album_revisions_action_check
+    CHECK (action IN ('create', 'update', 'delete', 'restore', 'revert', 'purge'));
@@ -9,6 +9,7 @@ export default function Home() {
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState<string | null>(null);
   const [editingId, setEditingId] = useState<number | null>(null);
+  const [editingVersion, setEditingVersion] = useState<number | undefined>(undefined);
   const [form, setForm] = useState<CreateAlbumInput>({
     title: '',
     artist: '',
@@ -38,7 +39,7 @@ export default function Home() {
 
     try {
       if (editingId !== null) {
-        await albumService.update(editingId, form);
+        await albumService.update(editingId, form, editingVersion);
       } else {
         await albumService.create(form);
       }
@@ -51,6 +52,7 @@ export default function Home() {
 
   const handleEdit = (album: Album) => {
     setEditingId(album.id);
+    setEditingVersion(album.version);
     setForm({
       title: album.title,
       artist: album.artist,
@@ -58,12 +60,12 @@ export default function Home() {
     });
   };
 
-  const handleDelete = async (id: number) => {
+  const handleDelete = async (id: number, version: number) => {
     if (!confirm('Are you sure you want to delete this album?')) return;
 
     try {
       setError(null);
-      await albumService.delete(id);
+      await albumService.delete(id, version);
       await loadAlbums();
     } catch (err) {
       setError(err instanceof Error ? err.message : 'Failed to delete album');
@@ -73,6 +75,7 @@ export default function Home() {
   const resetForm = () => {
     setForm({ title: '', artist: '', price: 0 });
     setEditingId(null);
+    setEditingVersion(undefined);
   };
 
   if (loading) {
@@ -195,7 +198,7 @@ export default function Home() {
                 </button>
                 <button
                   type="button"
-                  onClick={() => handleDelete(album.id)}
+                  onClick={() => handleDelete(album.id, album.version)}
                   className="flex-1 bg-red-500 text-white py-2 px-4 rounded-lg hover:bg-red-600 transition-colors"
                 >
                   Delete
@@ -5,7 +5,7 @@ import { Bot } from 'lucide-react';
 import { MessageComponent, type Message } from '../../components/chat/Message';
 import { ChatInput } from '../../components/chat/ChatInput';
 import { LoadingSpinner } from '../../components/common/LoadingSpinner';
-import { chatService, type ChatMessage } from '../../services/chatService';
+import { chatService, type ChatMessage, type PendingAction } from '../../services/chatService';
 
 export default function ChatPage() {
   const [messages, setMessages] = useState<Message[]>([
@@ -17,6 +17,7 @@ export default function ChatPage() {
     },
   ]);
   const [isLoading, setIsLoading] = useState(false);
+  const [pendingActions, setPendingActions] = useState<PendingAction[]>([]);
 
   const messagesEndRef = useRef<HTMLDivElement>(null);
 
@@ -62,6 +63,7 @@ export default function ChatPage() {
             timestamp: new Date(),
           };
           setMessages((prev) => [...prev, assistantMessage]);
+          setPendingActions(response.pending_actions ?? []);
         })
         .catch((error) => {
           console.error('Error sending message:', error);
@@ -85,6 +87,25 @@ export default function ChatPage() {
     });
   }, []);
 
+  const resolveAction = useCallback(async (action: PendingAction, confirmed: boolean) => {
+    setPendingActions((prev) => prev.filter((a) => a.token !== action.token));
+
+    let content = `Cancelled ${action.tool} (${action.arguments}).`;
+    if (confirmed) {
+      try {
+        const result = await chatService.confirmAction(action.token);
+        content = `Confirmed ${result.tool}: ${result.output}`;
+      } catch (error) {
+        content = `Could not run ${action.tool}: ${error instanceof Error ? error.message : 'unknown error'}`;
+      }
+    }
+
+    setMessages((prev) => [
+      ...prev,
+      { id: Date.now(), content, role: 'assistant', timestamp: new Date() },
+    ]);
+  }, []);
+
   const quickActions = [
     'Show me all albums',
     'Create a new album',
@@ -134,6 +155,34 @@ export default function ChatPage() {
             <div ref={messagesEndRef} />
           </div>
 
+          {/* Pending Actions */}
+          {pendingActions.length > 0 && (
+            <div className="p-4 border-t border-gray-100 bg-yellow-50">
+              <p className="text-sm text-gray-700 mb-2">These actions need your confirmation:</p>
+              {pendingActions.map((action) => (
+                <div key={action.token} className="flex items-center justify-between gap-2 mb-2">
+                  <code className="text-sm text-gray-800">
+                    {action.tool} {action.arguments}
+                  </code>
+                  <div className="flex gap-2">
+                    <button
+                      onClick={() => resolveAction(action, true)}
+                      className="text-sm px-3 py-1 bg-purple-600 text-white rounded-full hover:bg-purple-700 transition-colors"
+                    >
+                      Confirm
+                    </button>
+                    <button
+                      onClick={() => resolveAction(action, false)}
+                      className="text-sm px-3 py-1 bg-gray-200 text-gray-700 rounded-full hover:bg-gray-300 transition-colors"
+                    >
+                      Cancel
+                    </button>
+                  </div>
+                </div>
+              ))}
+            </div>
+          )}
+
           {/* Quick Actions */}
           {messages.length <= 1 && (
             <div className="p-4 border-t border-gray-100 bg-gray-50">
@@ -1,4 +1,4 @@
-import type { Album, CreateAlbumInput, UpdateAlbumInput } from '@/types/album';
+import type { Album, AlbumList, CreateAlbumInput, UpdateAlbumInput } from '@/types/album';
 
 const API_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080';
 
@@ -12,6 +12,11 @@ class AlbumServiceError extends Error {
   }
 }
 
+// ifMatch makes a write conditional on the album still being at the given version
+function ifMatch(version?: number): Record<string, string> {
+  return version === undefined ? {} : { 'If-Match': `"${version}"` };
+}
+
 export const albumService = {
   async getAll(): Promise<Album[]> {
     const response = await fetch(`${API_URL}/albums`, {
@@ -29,7 +34,8 @@ export const albumService = {
       );
     }
 
-    return response.json();
+    const page: AlbumList = await response.json();
+    return page.albums;
   },
 
   async getById(id: number): Promise<Album> {
@@ -67,15 +73,20 @@ export const albumService = {
     return response.json();
   },
 
-  async update(id: number, album: UpdateAlbumInput): Promise<Album> {
+  async update(id: number, album: UpdateAlbumInput, version?: number): Promise<Album> {
     const response = await fetch(`${API_URL}/albums/${id}`, {
       method: 'PUT',
       headers: {
         'Content-Type': 'application/json',
+        ...ifMatch(version),
       },
       body: JSON.stringify(album),
     });
 
+    if (response.status === 412) {
+      throw new AlbumServiceError(412, 'This album was changed by someone else. Reload and try again.');
+    }
+
     if (!response.ok) {
       throw new AlbumServiceError(
         response.status,
@@ -86,14 +97,19 @@ export const albumService = {
     return response.json();
   },
 
-  async delete(id: number): Promise<void> {
+  async delete(id: number, version?: number): Promise<void> {
     const response = await fetch(`${API_URL}/albums/${id}`, {
       method: 'DELETE',
       headers: {
         'Content-Type': 'application/json',
+        ...ifMatch(version),
       },
     });
 
+    if (response.status === 412) {
+      throw new AlbumServiceError(412, 'This album was changed by someone else. Reload and try again.');
+    }
+
     if (!response.ok) {
       throw new AlbumServiceError(
         response.status,
@@ -9,10 +9,24 @@ export interface ChatRequest {
   messages: ChatMessage[];
 }
 
+export interface PendingAction {
+  token: string;
+  tool_call_id: string;
+  tool: string;
+  arguments: string;
+  expires_at: string;
+}
+
+export interface ActionResult {
+  tool: string;
+  output: string;
+}
+
 export interface ChatResponse {
   message: string;
   tool_calls?: any[];
   tool_results?: any[];
+  pending_actions?: PendingAction[];
 }
 
 class ChatServiceError extends Error {
@@ -55,4 +69,24 @@ export const chatService = {
       );
     }
   },
+
+  async confirmAction(token: string): Promise<ActionResult> {
+    const response = await fetch(`${API_URL}/chat/actions/confirm`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify({ token }),
+    });
+
+    if (!response.ok) {
+      const errorData = await response.json().catch(() => ({}));
+      throw new ChatServiceError(
+        response.status,
+        errorData.error || `HTTP error! status: ${response.status}`
+      );
+    }
+
+    return response.json();
+  },
 };
@@ -3,11 +3,19 @@ export interface Album {
   title: string;
   artist: string;
   price: number;
+  currency: string;
+  prices?: PricePoint[];
+  version: number;
   created_at: string;
   updated_at: string;
   deleted_at?: string | null;
 }
 
+export interface PricePoint {
+  currency: string;
+  amount: number;
+}
+
 export interface CreateAlbumInput {
   title: string;
   artist: string;
@@ -19,3 +27,17 @@ export interface UpdateAlbumInput {
   artist: string;
   price: number;
 }
+
+export interface AlbumList {
+  albums: Album[];
+  total: number;
+  limit: number;
+  offset: number;
+  next_cursor?: string;
+  prev_cursor?: string;
+  links: {
+    self: string;
+    next?: string;
+    prev?: string;
+  };
+}